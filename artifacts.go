@@ -0,0 +1,152 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/neo-mode/runner-api"
+)
+
+type Artifact struct {
+	Name           string
+	Paths          []string
+	When           string
+	ExpireIn       string `json:"expire_in"`
+	ArtifactType   string `json:"artifact_type"`
+	ArtifactFormat string `json:"artifact_format"`
+}
+
+var reportTypes = map[string]string{
+	"junit":  "JUnit",
+	"dotenv": "dotenv",
+}
+
+func (jc *JobContext) uploadReports(isSuccess bool) {
+
+	for _, val := range jc.job.Artifacts {
+
+		var label, ok = reportTypes[val.ArtifactType]
+		if !ok || len(val.Paths) == 0 || !matchesWhen(artifactWhen(val.When), isSuccess) {
+			continue
+		}
+
+		var files []string
+		for _, pattern := range val.Paths {
+			var matches, _ = filepath.Glob(filepath.Join(jc.projDir, pattern))
+			files = append(files, matches...)
+		}
+
+		if files == nil {
+			jc.printTrace("No " + label + " reports found matching " + strconv.Quote(val.Paths[0]))
+			continue
+		}
+
+		if val.ArtifactType == "dotenv" && !jc.checkDotenv(files) {
+			continue
+		}
+
+		if err := jc.uploadArtifact(val, files); err != nil {
+			jc.printTrace("Uploading " + label + " reports failed: " + err.Error())
+			continue
+		}
+
+		jc.printTrace("Uploaded " + strconv.Itoa(len(files)) + " " + label + " report(s)")
+	}
+}
+
+func (jc *JobContext) checkDotenv(files []string) bool {
+
+	for _, name := range files {
+
+		var rel, _ = filepath.Rel(jc.projDir, name)
+		var variables, err = parseDotenv(name)
+		if err != nil {
+			jc.printTrace("Invalid dotenv report " + rel + ": " + err.Error())
+			return false
+		}
+
+		jc.printTrace("Dotenv report " + rel + " exports " + strconv.Itoa(len(variables)) + " variable(s)")
+	}
+
+	return true
+}
+
+func artifactWhen(when string) string {
+
+	if when == "" {
+		return "always"
+	}
+
+	return when
+}
+
+func (jc *JobContext) uploadArtifact(artifact Artifact, files []string) error {
+
+	var archive bytes.Buffer
+	for _, name := range files {
+
+		var f, err = os.Open(name)
+		if err != nil {
+			return err
+		}
+
+		var zw = gzip.NewWriter(&archive)
+		zw.Name, _ = filepath.Rel(jc.projDir, name)
+		_, err = io.Copy(zw, f)
+		f.Close()
+
+		if err != nil {
+			return err
+		}
+		zw.Close()
+	}
+
+	return jc.postArtifact(artifact.ArtifactType, "gzip", artifact.ExpireIn, artifact.ArtifactType+".gz", archive.Bytes())
+}
+
+func (jc *JobContext) postArtifact(artifactType, format, expireIn, fileName string, data []byte) error {
+
+	var body bytes.Buffer
+	var form = multipart.NewWriter(&body)
+	form.WriteField("artifact_type", artifactType)
+	form.WriteField("artifact_format", format)
+	if expireIn != "" {
+		form.WriteField("expire_in", expireIn)
+	}
+
+	var part, err = form.CreateFormFile("file", fileName)
+	if err != nil {
+		return err
+	}
+	part.Write(data)
+	form.Close()
+
+	var req *http.Request
+	req, err = http.NewRequest(http.MethodPost, apiURL("/jobs/"+jc.jobID+"/artifacts"), &body)
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	req.Header.Set("JOB-TOKEN", jc.job.Token)
+
+	var res *http.Response
+	if res, err = runner.Client.Do(req); err != nil {
+		return err
+	}
+
+	io.Copy(io.Discard, res.Body)
+	res.Body.Close()
+
+	if res.StatusCode != http.StatusCreated {
+		return runner.APIError(res.Status)
+	}
+
+	return nil
+}
@@ -0,0 +1,105 @@
+package main
+
+import "strings"
+
+type jobNeed struct {
+	capability string
+	detail     string
+	isRequired bool
+}
+
+var knownCapabilities = map[string]bool{"image": true, "services": true}
+
+func checkCapabilities() {
+
+	for _, val := range config.Custom.Capabilities {
+		if !knownCapabilities[val] {
+			printErr("Unknown custom executor capability: " + val)
+		}
+	}
+}
+
+func executorCapabilities(name string) []string {
+
+	if name == "custom" {
+		return config.Custom.Capabilities
+	}
+	return nil
+}
+
+func (jc *JobContext) jobNeeds() []jobNeed {
+
+	var needs []jobNeed
+	if len(jc.job.Services) > 0 {
+		needs = append(needs, jobNeed{"services", "services (" + jc.job.Services[0].Name + ")", true})
+	}
+	if jc.job.Image.Name != "" {
+		needs = append(needs, jobNeed{"image", "image " + jc.job.Image.Name, false})
+	}
+	return needs
+}
+
+func supports(name string, need jobNeed) bool {
+
+	for _, val := range executorCapabilities(name) {
+		if val == need.capability {
+			return true
+		}
+	}
+	return false
+}
+
+func (jc *JobContext) selectExecutor(configJob *ConfigJob) error {
+
+	var preferred = config.Executor
+	if configJob != nil && configJob.Executor != "" {
+		preferred = configJob.Executor
+	}
+	if preferred == "" {
+		preferred = "shell"
+	}
+
+	var candidates = append([]string{preferred}, config.Executors...)
+	var needs = jc.jobNeeds()
+
+	var best string
+	var bestScore = -1
+	for _, name := range candidates {
+
+		var score int
+		for _, need := range needs {
+			if supports(name, need) {
+				score++
+			} else if need.isRequired {
+				score = -1
+				break
+			}
+		}
+
+		if score > bestScore {
+			best, bestScore = name, score
+		}
+	}
+
+	if best == "" {
+		var missing []string
+		for _, need := range needs {
+			if need.isRequired {
+				missing = append(missing, need.detail)
+			}
+		}
+		return UnsupportedError("This runner cannot run this job because it requires " + strings.Join(missing, " and ") + ", which none of its executors (" + strings.Join(candidates, ", ") + ") supports")
+	}
+
+	jc.executorName = best
+	if best != preferred {
+		jc.printTrace("Using the " + best + " executor instead of " + preferred + " for this job's requirements")
+	}
+	for _, need := range needs {
+		if !supports(best, need) {
+			jc.printTrace("Ignoring " + need.detail + ": the " + best + " executor does not support it")
+		}
+	}
+
+	return nil
+}
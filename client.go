@@ -0,0 +1,168 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/neo-mode/runner-api"
+)
+
+const defaultURL = "https://gitlab.com"
+
+func apiURL(path string) string {
+	return defaultURL + "/api/v4" + path
+}
+
+func defineClient() {
+
+	var transport = http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: config.InsecureSkipVerify}
+
+	if config.Proxy != "" {
+		var proxy, err = url.Parse(config.Proxy)
+		if err != nil {
+			printErr(err.Error())
+		}
+		transport.Proxy = http.ProxyURL(proxy)
+	}
+
+	if config.CAFile != "" {
+		var data, err = os.ReadFile(config.CAFile)
+		if err != nil {
+			printErr(err.Error())
+		}
+
+		var pool, _ = x509.SystemCertPool()
+		if pool == nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(data) {
+			printErr("No certificates found in " + config.CAFile)
+		}
+		transport.TLSClientConfig.RootCAs = pool
+	}
+
+	if config.ClientCert != "" || config.ClientP12 != "" {
+		var cert, err = loadClientCert()
+		if err != nil {
+			printErr("Loading client certificate failed: " + err.Error())
+		}
+		transport.TLSClientConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	var next http.RoundTripper = transport
+	if config.Debug {
+		next = &debugTransport{next: transport}
+	}
+
+	var limiter = &rateLimitTransport{next: next}
+	runner.Client = &http.Client{Timeout: time.Second * config.ConnectionTimeout, Transport: limiter}
+	if config.URL == "" {
+		return
+	}
+
+	var base, err = parseBaseURL(config.URL)
+	if err != nil {
+		printErr("Invalid GitLab URL " + config.URL + ": " + err.Error())
+	}
+
+	runner.Client.Transport = &endpointTransport{base: base, next: limiter}
+}
+
+func parseBaseURL(text string) (*url.URL, error) {
+
+	var base, err = url.Parse(strings.TrimSuffix(strings.TrimSuffix(text, "/"), "/api/v4"))
+	if err != nil {
+		return nil, err
+	}
+
+	if base.Scheme != "http" && base.Scheme != "https" || base.Host == "" {
+		return nil, errors.New("expected an http or https URL with a host")
+	}
+
+	base.Path = strings.TrimSuffix(base.Path, "/")
+	return base, nil
+}
+
+func loadClientCert() (tls.Certificate, error) {
+
+	if config.ClientP12 == "" {
+		return tls.LoadX509KeyPair(config.ClientCert, config.ClientKey)
+	}
+
+	var cmd = exec.Command("openssl", "pkcs12", "-in", config.ClientP12, "-nodes", "-passin", "env:RUNNER_P12_PASSWORD")
+	cmd.Env = append(os.Environ(), "RUNNER_P12_PASSWORD="+config.ClientP12Password)
+
+	var data, err = cmd.Output()
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+
+	return tls.X509KeyPair(data, data)
+}
+
+type endpointTransport struct {
+	base *url.URL
+	next http.RoundTripper
+}
+
+func (t *endpointTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+
+	req = req.Clone(req.Context())
+	req.URL.Scheme = t.base.Scheme
+	req.URL.Host = t.base.Host
+	req.URL.Path = t.base.Path + req.URL.Path
+	req.Host = ""
+
+	return t.next.RoundTrip(req)
+}
+
+type debugTransport struct {
+	next http.RoundTripper
+}
+
+func (t *debugTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+
+	var start = time.Now()
+	var res, err = t.next.RoundTrip(req)
+	var latency = time.Since(start).Round(time.Millisecond).String()
+
+	var target = redactURL(req.URL)
+	if err != nil {
+		printLog("HTTP " + req.Method + " " + target + " failed after " + latency + ": " + err.Error())
+		return res, err
+	}
+
+	var line = "HTTP " + req.Method + " " + target + " " + res.Status + " in " + latency
+	if id := res.Header.Get("X-Request-Id"); id != "" {
+		line += ", request id " + id
+	}
+	printLog(line)
+
+	return res, err
+}
+
+func redactURL(u *url.URL) string {
+
+	var redacted = *u
+	if redacted.User != nil {
+		redacted.User = url.User("REDACTED")
+	}
+
+	var query = redacted.Query()
+	for key := range query {
+		if strings.Contains(strings.ToLower(key), "token") {
+			query.Set(key, "REDACTED")
+		}
+	}
+	redacted.RawQuery = query.Encode()
+
+	return redacted.String()
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const configEnv = "RUNNER_CONFIG"
+const configBackups = 5
+
+var configFlag string
+var dryRunFlag bool
+
+func parseGlobalFlags(args []string) []string {
+
+	var rest []string
+	for i := 0; i < len(args); i++ {
+
+		var val = args[i]
+		if val == "--config" || val == "-config" {
+			if i+1 == len(args) {
+				printErr("Flag " + val + " requires a config path")
+			}
+			i++
+			configFlag = args[i]
+
+		} else if strings.HasPrefix(val, "--config=") || strings.HasPrefix(val, "-config=") {
+			configFlag = val[strings.IndexByte(val, '=')+1:]
+
+		} else if val == "--dry-run" || val == "-dry-run" {
+			dryRunFlag = true
+
+		} else {
+			return append(rest, args[i:]...)
+		}
+	}
+
+	return rest
+}
+
+func defineConfigFile() {
+
+	if configFlag != "" {
+		configFile = configFlag
+		return
+	}
+
+	if configFile = os.Getenv(configEnv); configFile != "" {
+		return
+	}
+
+	var homeDir, _ = os.UserHomeDir()
+	if homeDir != "" {
+		var legacy = filepath.Join(homeDir, ".ci-config.json")
+		if _, err := os.Stat(legacy); err == nil {
+			configFile = legacy
+			return
+		}
+	}
+
+	if dir := dataDir(); dir != "" {
+		configFile = filepath.Join(dir, "config.json")
+		return
+	}
+
+	var configDir, err = os.UserConfigDir()
+	if err != nil {
+		printErr("Cannot locate the runner config: " + err.Error() + ". Pass --config or set " + configEnv)
+	}
+
+	configFile = filepath.Join(configDir, "neo-runner", "config.json")
+}
+
+func writeConfig() error {
+
+	var base, entry = splitConfig(config)
+	if configDoc == nil {
+		configDoc = base
+	}
+
+	var runners = configRunners()
+	if runnerIndex >= 0 && runnerIndex < len(runners) {
+		for key, val := range entry {
+			if _, ok := runners[runnerIndex][key]; ok || key == "Token" {
+				runners[runnerIndex][key] = val
+			}
+		}
+	} else {
+		runnerIndex = len(runners)
+		runners = append(runners, entry)
+	}
+
+	configDoc["Runners"], _ = json.Marshal(runners)
+	return writeConfigDocument()
+}
+
+func writeConfigDocument() error {
+
+	var data, err = json.MarshalIndent(configDoc, "", "\t")
+	if err != nil {
+		return err
+	}
+
+	var dir = filepath.Dir(configFile)
+	if err = os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+
+	var f *os.File
+	if f, err = os.CreateTemp(dir, ".config-*.tmp"); err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	_, err = f.Write(append(data, '\n'))
+	if err == nil {
+		err = f.Sync()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+
+	var written []byte
+	if written, err = os.ReadFile(f.Name()); err != nil {
+		return err
+	}
+	if err = json.Unmarshal(written, new(Config)); err != nil {
+		return err
+	}
+
+	if err = backupConfig(); err != nil {
+		printLog("Backing up " + configFile + " failed: " + err.Error())
+	}
+
+	return os.Rename(f.Name(), configFile)
+}
+
+func backupConfig() error {
+
+	var data, err = os.ReadFile(configFile)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var backup = configFile + "." + time.Now().Format("20060102-150405") + ".bak"
+	if err = os.WriteFile(backup, data, 0600); err != nil {
+		return err
+	}
+
+	var names, _ = filepath.Glob(configFile + ".*.bak")
+	sort.Strings(names)
+	for ; len(names) > configBackups; names = names[1:] {
+		os.Remove(names[0])
+	}
+	return nil
+}
+
+func defaultWorkDir() string {
+
+	if dir := dataDir(); dir != "" {
+		return filepath.Join(dir, "work")
+	}
+	if homeDir, _ := os.UserHomeDir(); homeDir != "" {
+		return filepath.Join(homeDir, ".ci")
+	}
+	return filepath.Join(filepath.Dir(configFile), "ci")
+}
+
+func isDryRun() bool {
+	return dryRunFlag || config.DryRun
+}
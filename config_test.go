@@ -0,0 +1,42 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"testing"
+)
+
+func TestWriteConfigDocument(t *testing.T) {
+
+	var dir = t.TempDir()
+	configFile = filepath.Join(dir, "config.json")
+
+	for i := 1; i <= configBackups+1; i++ {
+		os.WriteFile(configFile+".20000101-00000"+strconv.Itoa(i)+".bak", []byte("{}"), 0600)
+	}
+
+	for _, name := range []string{"first", "second", "third"} {
+		configDoc = map[string]json.RawMessage{"Name": json.RawMessage(`"` + name + `"`)}
+		if err := writeConfigDocument(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var names, _ = filepath.Glob(configFile + ".*.bak")
+	sort.Strings(names)
+	if len(names) != configBackups {
+		t.Fatalf("Config directory contains backups %q, want %d", names, configBackups)
+	}
+	if _, err := os.Stat(configFile + ".20000101-000001.bak"); !os.IsNotExist(err) {
+		t.Error("Oldest backup was not removed")
+	}
+
+	var data, _ = os.ReadFile(names[len(names)-1])
+	var backup Config
+	if err := json.Unmarshal(data, &backup); err != nil || backup.Name != "second" {
+		t.Errorf("Latest backup = %s, want the previous config", data)
+	}
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+const dataDirEnv = "RUNNER_DATA_DIR"
+const defaultContainerDataDir = "/data"
+
+var containerMarkers = []string{"/.dockerenv", "/run/.containerenv"}
+var containerCgroups = [][]byte{[]byte("docker"), []byte("kubepods"), []byte("containerd"), []byte("libpod")}
+
+var envDoc map[string]json.RawMessage
+
+func isContainer() bool {
+
+	for _, val := range containerMarkers {
+		if _, err := os.Stat(val); err == nil {
+			return true
+		}
+	}
+
+	var data, _ = os.ReadFile("/proc/1/cgroup")
+	for _, val := range containerCgroups {
+		if bytes.Contains(data, val) {
+			return true
+		}
+	}
+	return false
+}
+
+func dataDir() string {
+
+	if dir := os.Getenv(dataDirEnv); dir != "" {
+		return dir
+	}
+	if isContainer() {
+		return defaultContainerDataDir
+	}
+	return ""
+}
+
+func defineEnvConfig() {
+
+	envDoc = map[string]json.RawMessage{}
+
+	var typ = reflect.TypeOf(Config{})
+	for i := 0; i < typ.NumField(); i++ {
+
+		var field = typ.Field(i)
+		if field.Name == "Runners" {
+			continue
+		}
+
+		var key = "RUNNER_" + envName(field.Name)
+		if isRunnerExported(key) {
+			continue
+		}
+
+		var value, ok = os.LookupEnv(key)
+		if !ok {
+			continue
+		}
+		os.Unsetenv(key)
+
+		if field.Type.Kind() != reflect.String && json.Valid([]byte(value)) {
+			envDoc[field.Name] = json.RawMessage(value)
+		} else {
+			envDoc[field.Name], _ = json.Marshal(value)
+		}
+	}
+}
+
+func envName(name string) string {
+
+	var out []rune
+	var runes = []rune(name)
+	for i, c := range runes {
+		if i > 0 && unicode.IsUpper(c) && (!unicode.IsUpper(runes[i-1]) || i+1 < len(runes) && unicode.IsLower(runes[i+1])) {
+			out = append(out, '_')
+		}
+		out = append(out, unicode.ToUpper(c))
+	}
+	return string(out)
+}
+
+func hasEnvRunner() bool {
+
+	for _, key := range []string{"RUNNER_TOKEN", "RUNNER_TOKEN_COMMAND", "RUNNER_TOKENS"} {
+		if _, ok := os.LookupEnv(key); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func applyEnvConfig() {
+
+	for key, val := range envDoc {
+		if err := json.Unmarshal([]byte(`{"`+key+`":`+string(val)+`}`), &config); err != nil {
+			printErr("Invalid value in RUNNER_" + envName(key) + ": " + err.Error())
+		}
+	}
+}
+
+func checkVolume(dir string) {
+
+	var f, err = os.CreateTemp(dir, ".write-test-")
+	if err == nil {
+		f.Close()
+		os.Remove(f.Name())
+		return
+	}
+
+	var text = "Directory " + dir + " is not writable by uid " + strconv.Itoa(os.Geteuid())
+	if info, statErr := os.Stat(dir); statErr == nil {
+		if uid, ok := fileOwner(info); ok {
+			text += " (owned by uid " + strconv.FormatUint(uint64(uid), 10) + ")"
+		}
+	}
+	printErr(text + ", run the container with a matching --user or change the volume ownership")
+}
+
+func parseNumericUser(name string) (*jobUser, bool) {
+
+	var uidText, gidText, hasGid = strings.Cut(name, ":")
+	var uid, err = strconv.ParseUint(uidText, 10, 32)
+	if err != nil {
+		return nil, false
+	}
+
+	var gid = uid
+	if hasGid {
+		if gid, err = strconv.ParseUint(gidText, 10, 32); err != nil {
+			return nil, false
+		}
+	}
+
+	var credential = &userCredential{Uid: uint32(uid), Gid: uint32(gid)}
+	return &jobUser{name: uidText, home: "/tmp", credential: credential}, true
+}
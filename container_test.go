@@ -0,0 +1,64 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"testing"
+)
+
+func TestEnvName(t *testing.T) {
+
+	var tests = map[string]string{
+		"URL":                   "URL",
+		"WorkDir":               "WORK_DIR",
+		"CAFile":                "CA_FILE",
+		"TokenCommand":          "TOKEN_COMMAND",
+		"ClientP12Password":     "CLIENT_P12_PASSWORD",
+		"DebugSnapshotExpireIn": "DEBUG_SNAPSHOT_EXPIRE_IN",
+	}
+
+	for name, want := range tests {
+		if got := envName(name); got != want {
+			t.Errorf("envName(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestDefineEnvConfig(t *testing.T) {
+
+	t.Setenv("RUNNER_URL", "https://gitlab.example.com")
+	t.Setenv("RUNNER_WORK_DIR", "/data/work")
+	t.Setenv("RUNNER_DEBUG", "true")
+	t.Setenv("RUNNER_TOKEN_COMMAND", `["pass", "runner"]`)
+	t.Setenv("RUNNER_SHELL", `["not", "json", "for", "a", "string"]`)
+	t.Setenv("RUNNER_CACHE_DIR", "/exported/by/the/runner")
+
+	defineEnvConfig()
+
+	for _, key := range []string{"RUNNER_URL", "RUNNER_WORK_DIR", "RUNNER_DEBUG", "RUNNER_TOKEN_COMMAND", "RUNNER_SHELL"} {
+		if _, ok := os.LookupEnv(key); ok {
+			t.Errorf("%s is still set after reading it", key)
+		}
+	}
+	if _, ok := envDoc["CacheDir"]; ok {
+		t.Error("RUNNER_CACHE_DIR is exported to jobs and must not be read as config")
+	}
+
+	config = Config{}
+	applyEnvConfig()
+
+	if config.URL != "https://gitlab.example.com" || config.WorkDir != "/data/work" || !config.Debug {
+		t.Errorf("config = %+v", config)
+	}
+	if len(config.TokenCommand) != 2 || config.TokenCommand[1] != "runner" {
+		t.Errorf("TokenCommand = %q", config.TokenCommand)
+	}
+	if config.Shell != `["not", "json", "for", "a", "string"]` {
+		t.Errorf("Shell = %q", config.Shell)
+	}
+
+	var data, _ = json.Marshal(envDoc["Debug"])
+	if string(data) != "true" {
+		t.Errorf("Debug document = %s", data)
+	}
+}
@@ -0,0 +1,204 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+type JobStatus struct {
+	ID         string     `json:"id"`
+	ProjectID  string     `json:"project_id"`
+	Name       string     `json:"name"`
+	StartedAt  time.Time  `json:"started_at"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+	PID        int        `json:"pid,omitempty"`
+	State      string     `json:"state,omitempty"`
+	Failure    string     `json:"failure_reason,omitempty"`
+}
+
+type CanceledError string
+
+const historySize = 50
+
+var isPaused atomic.Bool
+var startedAt = time.Now()
+
+var jobStatus struct {
+	sync.Mutex
+	running *JobStatus
+	context *JobContext
+	history []JobStatus
+}
+
+func startJobStatus(jc *JobContext) {
+
+	jobStatus.Lock()
+	jobStatus.running = &JobStatus{ID: jc.jobID, ProjectID: jc.projID, Name: jc.job.JobInfo.Name, StartedAt: time.Now()}
+	jobStatus.context = jc
+	jobStatus.Unlock()
+}
+
+func setJobPID(pid int) {
+
+	jobStatus.Lock()
+	if jobStatus.running != nil {
+		jobStatus.running.PID = pid
+	}
+	jobStatus.Unlock()
+}
+
+func finishJobStatus(state State) {
+
+	jobStatus.Lock()
+	defer jobStatus.Unlock()
+
+	if jobStatus.running == nil {
+		return
+	}
+
+	var now = time.Now()
+	var status = *jobStatus.running
+	status.FinishedAt = &now
+	status.PID = 0
+	status.State = state.State
+	status.Failure = state.Failure
+
+	jobStatus.running = nil
+	jobStatus.context = nil
+	jobStatus.history = append(jobStatus.history, status)
+	if len(jobStatus.history) > historySize {
+		jobStatus.history = jobStatus.history[1:]
+	}
+}
+
+func serveControl() {
+
+	if config.ControlSocket == "" {
+		return
+	}
+
+	os.Remove(config.ControlSocket)
+	var listener, err = net.Listen("unix", config.ControlSocket)
+	if err != nil {
+		printErr(err.Error())
+	}
+	os.Chmod(config.ControlSocket, 0600)
+
+	var mux = http.NewServeMux()
+	mux.HandleFunc("/health", handleHealth)
+	mux.HandleFunc("/jobs", handleJobs)
+	mux.HandleFunc("/jobs/", handleCancel)
+	mux.HandleFunc("/stats", handleStats)
+	mux.HandleFunc("/pause", handlePause)
+	mux.HandleFunc("/resume", handlePause)
+
+	go http.Serve(listener, mux)
+}
+
+func handleHealth(w http.ResponseWriter, r *http.Request) {
+
+	var status = "running"
+	if isStopping.Load() {
+		status = "stopping"
+	} else if isPaused.Load() {
+		status = "paused"
+	} else if isMaintenance.Load() {
+		status = "maintenance"
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"status":         status,
+		"version":        version,
+		"uptime_seconds": int(time.Since(startedAt).Seconds()),
+	})
+}
+
+func handleJobs(w http.ResponseWriter, r *http.Request) {
+
+	jobStatus.Lock()
+	defer jobStatus.Unlock()
+
+	var running = []JobStatus{}
+	if jobStatus.running != nil {
+		running = append(running, *jobStatus.running)
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{"running": running, "history": jobStatus.history})
+}
+
+func handleStats(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, loadUsage())
+}
+
+func handleCancel(w http.ResponseWriter, r *http.Request) {
+
+	var id = strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/jobs/"), "/cancel")
+	if r.Method != http.MethodPost || !strings.HasSuffix(r.URL.Path, "/cancel") {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "not found"})
+		return
+	}
+
+	jobStatus.Lock()
+	var jc = jobStatus.context
+	jobStatus.Unlock()
+
+	if jc == nil || jc.jobID != id {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "job " + id + " is not running"})
+		return
+	}
+
+	jc.abort(CanceledError("Job was canceled by the runner operator"))
+	writeJSON(w, http.StatusAccepted, map[string]string{"status": "canceling"})
+}
+
+func handlePause(w http.ResponseWriter, r *http.Request) {
+
+	if r.Method != http.MethodPost {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return
+	}
+
+	isPaused.Store(r.URL.Path == "/pause")
+	handleHealth(w, r)
+}
+
+func controlCommand(name string) {
+
+	if config.ControlSocket == "" {
+		printErr("ControlSocket is not configured")
+	}
+
+	var client = &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, "unix", config.ControlSocket)
+		},
+	}}
+
+	var res, err = client.Post("http://runner/"+name, "application/json", nil)
+	if err != nil {
+		printErr(err.Error())
+	}
+
+	io.Copy(os.Stdout, res.Body)
+	res.Body.Close()
+	os.Exit(0)
+}
+
+func writeJSON(w http.ResponseWriter, code int, data any) {
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(data)
+}
+
+func (err CanceledError) Error() string {
+	return string(err)
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"os/exec"
+)
+
+type CustomConfig struct {
+	PrepareExec string
+	PrepareArgs []string
+	RunExec     string
+	RunArgs     []string
+	CleanupExec string
+	CleanupArgs []string
+
+	Capabilities []string
+}
+
+type CustomContext struct {
+	JobID      string
+	JobName    string
+	Stage      string
+	ProjectID  string
+	ProjectDir string
+	ScriptDir  string
+	Sha        string
+	Image      Image
+	Services   []Service
+	Variables  map[string]string
+}
+
+type customExecutor struct {
+	context []byte
+	env     []string
+}
+
+func newCustomExecutor(jc *JobContext) Executor {
+
+	var context = CustomContext{
+		JobID:      jc.jobID,
+		JobName:    jc.job.JobInfo.Name,
+		Stage:      jc.job.JobInfo.Stage,
+		ProjectID:  jc.projID,
+		ProjectDir: jc.projDir,
+		ScriptDir:  jc.scriptDir,
+		Sha:        jc.job.GitInfo.Sha,
+		Image:      jc.job.Image,
+		Services:   jc.job.Services,
+		Variables:  map[string]string{},
+	}
+
+	var env = []string{"RUNNER_JOB_ID=" + context.JobID, "RUNNER_PROJECT_DIR=" + jc.projDir}
+	for _, val := range jc.job.Variables {
+		if val.Public {
+			context.Variables[val.Key] = val.Value
+			env = append(env, "CUSTOM_ENV_"+val.Key+"="+val.Value)
+		}
+	}
+
+	var data, _ = json.Marshal(context)
+	return &customExecutor{context: data, env: env}
+}
+
+func (e *customExecutor) Prepare(jc *JobContext, src Source) error {
+
+	if err := (shellExecutor{}).Prepare(jc, src); err != nil {
+		return err
+	}
+
+	if config.Custom.PrepareExec == "" {
+		return nil
+	}
+
+	return e.call(jc, jc.ctx, config.Custom.PrepareExec, config.Custom.PrepareArgs)
+}
+
+func (e *customExecutor) Run(jc *JobContext, name string, args []string, stdin []string) error {
+
+	if config.Custom.RunExec == "" {
+		return UnsupportedError("Custom executor has no RunExec configured")
+	}
+
+	var script, err = os.CreateTemp("", "runner-script-*")
+	if err != nil {
+		return err
+	}
+
+	for _, val := range stdin {
+		script.WriteString(val + "\n")
+	}
+	script.Close()
+
+	var runArgs = append(append([]string{}, config.Custom.RunArgs...), script.Name(), name)
+	var cmd = exec.Command(config.Custom.RunExec, append(runArgs, args...)...)
+	cmd.Dir = jc.scriptDir
+	cmd.Env = append(jc.environ(), e.env...)
+
+	err = jc.runCmd(cmd, nil)
+	os.Remove(script.Name())
+
+	return err
+}
+
+func (e *customExecutor) Cleanup(jc *JobContext) {
+
+	if config.Custom.CleanupExec == "" {
+		return
+	}
+
+	if err := e.call(jc, context.Background(), config.Custom.CleanupExec, config.Custom.CleanupArgs); err != nil {
+		jc.printTrace("Custom executor cleanup failed: " + err.Error())
+	}
+}
+
+func (e *customExecutor) call(jc *JobContext, ctx context.Context, name string, args []string) error {
+
+	var cmd = exec.CommandContext(ctx, name, args...)
+	cmd.Dir = jc.projDir
+	cmd.Env = append(jc.environ(), e.env...)
+	cmd.Stdin = bytes.NewReader(e.context)
+	cmd.Stdout = jc.traceWriter
+	cmd.Stderr = jc.traceWriter
+
+	return cmd.Run()
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"time"
+)
+
+func debugSocket(jobID string) string {
+	return config.WorkDir + "/.debug/" + jobID + ".sock"
+}
+
+func (jc *JobContext) serveDebugTerminal() {
+
+	if config.DebugTerminal <= 0 {
+		return
+	}
+
+	var socket = debugSocket(jc.jobID)
+	os.MkdirAll(config.WorkDir+"/.debug", 0700)
+	os.Remove(socket)
+
+	var listener, err = net.Listen("unix", socket)
+	if err != nil {
+		jc.printTrace("Starting debug terminal failed: " + err.Error())
+		return
+	}
+
+	var timeout = time.Minute * time.Duration(config.DebugTerminal)
+	jc.printTrace("Debug terminal is available for " + strconv.Itoa(config.DebugTerminal) + " minutes, attach with: runner attach " + jc.jobID)
+	printLog("Debug terminal for job " + jc.jobID + " is listening on " + socket)
+
+	listener.(*net.UnixListener).SetDeadline(time.Now().Add(timeout))
+	var conn net.Conn
+	conn, err = listener.Accept()
+	listener.Close()
+	os.Remove(socket)
+
+	if err != nil {
+		jc.printTrace("Debug terminal timed out without a session")
+		return
+	}
+
+	var cancel context.CancelFunc
+	jc.stepCtx, cancel = context.WithTimeout(jc.ctx, timeout)
+	jc.terminal = conn
+
+	jc.executor.Run(jc, jc.shell, []string{"-i"}, nil)
+
+	jc.terminal = nil
+	cancel()
+	jc.stepCtx = jc.ctx
+	conn.Close()
+
+	jc.printTrace("Debug terminal session finished")
+}
+
+func attachDebugTerminal(args []string) {
+
+	if len(args) != 1 {
+		printErr("Usage: runner attach <job id>")
+	}
+
+	var conn, err = net.Dial("unix", debugSocket(args[0]))
+	if err != nil {
+		printErr(err.Error())
+	}
+
+	go func() {
+		io.Copy(conn, os.Stdin)
+		conn.(*net.UnixConn).CloseWrite()
+	}()
+
+	io.Copy(os.Stdout, conn)
+	os.Exit(0)
+}
@@ -0,0 +1,225 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/neo-mode/runner-api"
+)
+
+const maxClockSkew = 30 * time.Second
+
+type doctorCheck struct {
+	name string
+	fn   func() (string, error)
+}
+
+func doctor() {
+
+	var isFailed bool
+	for i := range runnerConfigs {
+
+		useRunner(i)
+		if len(runnerConfigs) > 1 {
+			os.Stdout.WriteString("Runner " + strconv.Itoa(i+1) + " (" + config.URL + ")\n")
+		}
+
+		var serverTime time.Time
+		var checks = []doctorCheck{
+			{"GitLab API", func() (string, error) { return checkAPI(&serverTime) }},
+			{"Clock", func() (string, error) { return checkClock(serverTime) }},
+			{"Git", checkGit},
+			{"Shell", checkShells},
+			{"Work directory", checkWorkDir},
+			{"Disk space", checkDiskSpace},
+			{"Executor", checkExecutorPrerequisites},
+		}
+
+		if !runChecks(checks) {
+			isFailed = true
+		}
+	}
+
+	if isFailed {
+		os.Exit(1)
+	}
+	os.Exit(0)
+}
+
+func runChecks(checks []doctorCheck) bool {
+
+	var isFailed bool
+	for _, check := range checks {
+
+		var detail, err = check.fn()
+		if err != nil {
+			isFailed = true
+			os.Stdout.WriteString("[FAIL] " + check.name + ": " + err.Error() + "\n")
+			continue
+		}
+		os.Stdout.WriteString("[ OK ] " + check.name + ": " + detail + "\n")
+	}
+
+	return !isFailed
+}
+
+func checkAPI(serverTime *time.Time) (string, error) {
+
+	var tokens = runnerTokens()
+	if len(tokens) == 0 {
+		return "", errors.New("no runner token configured")
+	}
+
+	for _, token := range tokens {
+
+		var res, err = runner.Client.PostForm(apiURL("/runners/verify"), url.Values{"token": []string{token}})
+		if err != nil {
+			return "", err
+		}
+		res.Body.Close()
+
+		if date, err := http.ParseTime(res.Header.Get("Date")); err == nil {
+			*serverTime = date
+		}
+		if res.StatusCode != http.StatusOK {
+			return "", errors.New("verifying runner token failed: " + res.Status)
+		}
+	}
+
+	return strconv.Itoa(len(tokens)) + " runner token(s) verified", nil
+}
+
+func checkClock(serverTime time.Time) (string, error) {
+
+	if serverTime.IsZero() {
+		return "", errors.New("server time is unknown")
+	}
+
+	var skew = time.Since(serverTime).Round(time.Second)
+	if skew > maxClockSkew || skew < -maxClockSkew {
+		return "", errors.New("local clock differs from GitLab by " + skew.String())
+	}
+	return "skew " + skew.String(), nil
+}
+
+func checkGit() (string, error) {
+
+	var data, err = exec.Command("git", "--version").Output()
+	if err != nil {
+		return "", errors.New("git is not available: " + err.Error())
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+func checkShells() (string, error) {
+
+	var names = []string{config.Shell}
+	for _, val := range config.Jobs {
+		if val.Shell != "" {
+			names = append(names, val.Shell)
+		}
+	}
+
+	var found []string
+	for _, name := range names {
+		if name == "" {
+			name = "sh"
+		}
+		var path, err = exec.LookPath(name)
+		if err != nil {
+			return "", errors.New("shell " + name + " is not available")
+		}
+		found = append(found, path)
+	}
+
+	return strings.Join(found, ", "), nil
+}
+
+func checkWorkDir() (string, error) {
+
+	if err := os.MkdirAll(config.WorkDir, 0755); err != nil {
+		return "", err
+	}
+
+	var f, err = os.CreateTemp(config.WorkDir, ".doctor-")
+	if err != nil {
+		return "", errors.New(config.WorkDir + " is not writable: " + err.Error())
+	}
+	f.Close()
+	os.Remove(f.Name())
+
+	return config.WorkDir + " is writable", nil
+}
+
+func checkDiskSpace() (string, error) {
+
+	var free, err = freeSpace(config.WorkDir)
+	if err != nil {
+		return "", err
+	}
+
+	var detail = strconv.FormatInt(free, 10) + " MB free"
+	if config.MinFreeSpace > 0 && free < config.MinFreeSpace {
+		return "", errors.New(detail + ", " + strconv.FormatInt(config.MinFreeSpace, 10) + " MB required")
+	}
+
+	return detail, nil
+}
+
+func checkExecutorPrerequisites() (string, error) {
+
+	var names = append([]string{config.Executor}, config.Executors...)
+	for _, val := range config.Jobs {
+		names = append(names, val.Executor)
+	}
+
+	var isCustom, isSandbox bool
+	for _, name := range names {
+		isCustom = isCustom || name == "custom"
+		isSandbox = isSandbox || name == "sandbox"
+	}
+	if !isCustom && !isSandbox {
+		return "shell executor has no prerequisites", nil
+	}
+
+	var details []string
+	if isSandbox {
+		if err := checkSandbox(); err != nil {
+			return "", errors.New("sandbox executor " + err.Error())
+		}
+		for _, val := range config.Jobs {
+			if val.Executor == "sandbox" && val.User == "" && config.User == "" {
+				return "", errors.New("sandbox executor requires a job user, job " + val.JobName + " has none")
+			}
+		}
+		for _, name := range append([]string{config.Executor}, config.Executors...) {
+			if name == "sandbox" && config.User == "" {
+				return "", errors.New("sandbox executor requires a job user, configure User")
+			}
+		}
+		details = append(details, "sandbox executor can isolate jobs")
+	}
+
+	if isCustom {
+		for _, name := range []string{config.Custom.PrepareExec, config.Custom.RunExec, config.Custom.CleanupExec} {
+			if name == "" {
+				continue
+			}
+			if _, err := exec.LookPath(name); err != nil {
+				return "", errors.New("custom executor command " + name + " is not available")
+			}
+		}
+		if config.Custom.RunExec == "" {
+			return "", errors.New("custom executor has no RunExec")
+		}
+		details = append(details, "custom executor commands are available")
+	}
+
+	return strings.Join(details, ", "), nil
+}
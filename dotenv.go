@@ -0,0 +1,46 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"os"
+	"regexp"
+	"strconv"
+)
+
+const maxDotenvVariables = 20
+
+var dotenvKey = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+func parseDotenv(name string) (map[string]string, error) {
+
+	var data, err = os.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+
+	var variables = map[string]string{}
+	var scanner = bufio.NewScanner(bytes.NewReader(data))
+
+	for line := 1; scanner.Scan(); line++ {
+
+		var text = bytes.TrimSpace(scanner.Bytes())
+		if len(text) == 0 || text[0] == '#' {
+			continue
+		}
+
+		var i = bytes.IndexByte(text, '=')
+		if i <= 0 || !dotenvKey.Match(bytes.TrimSpace(text[:i])) {
+			return nil, errors.New("invalid variable on line " + strconv.Itoa(line))
+		}
+
+		variables[string(bytes.TrimSpace(text[:i]))] = string(text[i+1:])
+	}
+
+	if len(variables) > maxDotenvVariables {
+		return nil, errors.New("more than " + strconv.Itoa(maxDotenvVariables) + " variables")
+	}
+
+	return variables, scanner.Err()
+}
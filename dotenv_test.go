@@ -0,0 +1,51 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestParseDotenv(t *testing.T) {
+
+	var many strings.Builder
+	for i := 0; i <= maxDotenvVariables; i++ {
+		many.WriteString("KEY_" + strconv.Itoa(i) + "=x\n")
+	}
+
+	var tests = []struct {
+		name    string
+		data    string
+		want    map[string]string
+		isValid bool
+	}{
+		{"empty", "", map[string]string{}, true},
+		{"values", "A=1\n# comment\n\n B = two words\nC=\nD=a=b\n", map[string]string{"A": "1", "B": " two words", "C": "", "D": "a=b"}, true},
+		{"crlf", "A=1\r\nB=2\r\n", map[string]string{"A": "1", "B": "2"}, true},
+		{"missing key", "=1\n", nil, false},
+		{"missing separator", "A\n", nil, false},
+		{"invalid key", "1A=x\n", nil, false},
+		{"too many", many.String(), nil, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+
+			var name = filepath.Join(t.TempDir(), "build.env")
+			if err := os.WriteFile(name, []byte(test.data), 0600); err != nil {
+				t.Fatal(err)
+			}
+
+			var got, err = parseDotenv(name)
+			if (err == nil) != test.isValid {
+				t.Fatalf("parseDotenv error = %v, want valid %v", err, test.isValid)
+			}
+			if test.isValid && !reflect.DeepEqual(got, test.want) {
+				t.Errorf("parseDotenv = %#v, want %#v", got, test.want)
+			}
+		})
+	}
+}
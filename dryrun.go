@@ -0,0 +1,99 @@
+package main
+
+import (
+	"sort"
+	"strings"
+)
+
+func (jc *JobContext) dryRun(configJob *ConfigJob, src Source) error {
+
+	jc.printTrace("Dry run: commands are resolved and printed, but not executed")
+
+	if configJob != nil {
+		jc.printTrace("Matched config job " + configJob.JobName + " for project " + jc.projID)
+	} else {
+		jc.printTrace("No config job matched, running the payload steps")
+	}
+
+	if err := jc.defineExecutor(); err != nil {
+		return err
+	}
+	jc.defineGitCredentials()
+
+	jc.printTrace("Executor: " + jc.executorName + ", shell: " + jc.shell)
+	jc.printTrace("Project directory: " + jc.projDir + ", working directory: " + jc.scriptDir)
+	jc.printTrace("Repository: " + jc.job.GitInfo.RepoURL)
+	for _, val := range src.Mirrors {
+		jc.printTrace("Mirror: " + val)
+	}
+
+	if src.IsMerge {
+		jc.printTrace("Checkout: merge origin/" + src.SourceName + " into origin/" + src.TargetName + " (merge request " + src.MergeID + ")")
+	} else {
+		jc.printTrace("Checkout: " + jc.job.GitInfo.Sha)
+	}
+
+	if configJob != nil {
+		jc.printTrace("Command: " + strings.Join(append([]string{configJob.Cmd}, configJob.Args...), " "))
+		for _, val := range configJob.Stdin {
+			jc.printTrace("  " + val)
+		}
+	} else {
+		for _, step := range jc.job.Steps {
+
+			var when = step.When
+			if when == "" {
+				when = "on_success"
+			}
+			jc.printTrace("Step " + step.Name + " (when " + when + "):")
+
+			var shell, args, stdin = jc.shellCommand(stepScript(step))
+			jc.printTrace("  " + strings.Join(append([]string{shell}, args...), " "))
+			for _, val := range stdin {
+				jc.printTrace("    " + val)
+			}
+		}
+	}
+
+	jc.printTrace("Environment:")
+
+	var secrets = map[string]Secret{}
+	for key, val := range config.Secrets {
+		secrets[key] = val
+	}
+	if configJob != nil {
+		for key, val := range configJob.Secrets {
+			secrets[key] = val
+		}
+	}
+
+	var env = map[string]string{}
+	for key, val := range config.Variables {
+		env[key] = val
+	}
+	for _, val := range jc.job.Variables {
+		if val.Public {
+			env[val.Key] = val.Value
+		}
+	}
+	if configJob != nil {
+		for key, val := range configJob.Variables {
+			env[key] = val
+		}
+	}
+	for key, val := range secrets {
+		env[key] = "<secret from " + val.Provider + ">"
+	}
+
+	var names []string
+	for key := range env {
+		names = append(names, key)
+	}
+	sort.Strings(names)
+
+	for _, key := range names {
+		jc.printTrace("  " + key + "=" + env[key])
+	}
+
+	return nil
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runner/gitlabtest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRunJob(t *testing.T) {
+
+	var dir = t.TempDir()
+	var repo, sha = newTestRepo(t, dir)
+	var bin = buildRunner(t, dir)
+
+	var server = gitlabtest.NewServer()
+	defer server.Close()
+
+	server.AddJob(gitlabtest.Job{
+		ID:        1,
+		JobInfo:   gitlabtest.JobInfo{Name: "test", ProjectID: 1},
+		GitInfo:   gitlabtest.GitInfo{RepoURL: repo, Sha: sha},
+		Variables: []gitlabtest.Variable{{Key: "SECRET", Value: "hunter22", Public: true, Masked: true}},
+		Steps:     []gitlabtest.Step{{Name: "script", Script: []string{"cat hello.txt", "sleep 5", "echo secret=$SECRET"}}},
+	})
+
+	var out bytes.Buffer
+	var cmd = runnerCommand(t, bin, server, dir)
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+
+	var isStreamed bool
+	for i := 0; i < 40 && !isStreamed; i++ {
+		time.Sleep(100 * time.Millisecond)
+		isStreamed = strings.Contains(server.Trace(1), "hello from the repository")
+	}
+
+	if err := cmd.Wait(); err != nil {
+		t.Fatalf("Runner failed: %v\n%s", err, out.String())
+	}
+	if !isStreamed {
+		t.Error("Trace was not uploaded while the job was running")
+	}
+
+	var state, ok = server.State(1)
+	if !ok || state.State != "success" {
+		t.Errorf("Job state = %+v, want success", state)
+	}
+
+	var trace = server.Trace(1)
+	if !strings.Contains(trace, "hello from the repository") {
+		t.Errorf("Trace is missing the script output:\n%s", trace)
+	}
+	if strings.Contains(trace, "hunter22") || !strings.Contains(trace, "secret=[MASKED]") {
+		t.Errorf("Trace is not masked:\n%s", trace)
+	}
+}
+
+func TestRetryFromJobPayload(t *testing.T) {
+
+	var dir = t.TempDir()
+	var repo, sha = newTestRepo(t, dir)
+	var bin = buildRunner(t, dir)
+
+	var server = gitlabtest.NewServer()
+	defer server.Close()
+
+	server.AddJob(gitlabtest.Job{
+		ID:      1,
+		JobInfo: gitlabtest.JobInfo{Name: "flaky", ProjectID: 1},
+		GitInfo: gitlabtest.GitInfo{RepoURL: repo, Sha: sha},
+		Steps:   []gitlabtest.Step{{Name: "script", Script: []string{"test -f $TMPDIR/attempt || { touch $TMPDIR/attempt; exit 1; }", "echo passed on retry"}}},
+		Retry:   &gitlabtest.Retry{Max: 1, When: []string{"script_failure"}},
+	})
+
+	if out, err := runnerCommand(t, bin, server, dir).CombinedOutput(); err != nil {
+		t.Fatalf("Runner failed: %v\n%s", err, out)
+	}
+
+	var state, _ = server.State(1)
+	var trace = server.Trace(1)
+	if state.State != "success" || !strings.Contains(trace, "Attempt 2 of 2") || !strings.Contains(trace, "passed on retry") {
+		t.Errorf("Job state = %+v, trace:\n%s", state, trace)
+	}
+}
+
+func newTestRepo(t *testing.T, dir string) (string, string) {
+
+	if testing.Short() {
+		t.Skip("Building the runner is skipped in short mode")
+	}
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	var repo = filepath.Join(dir, "repo")
+	var git = func(args ...string) string {
+		var cmd = exec.Command("git", append([]string{"-C", repo}, args...)...)
+		cmd.Env = append(os.Environ(), "GIT_AUTHOR_NAME=Test", "GIT_AUTHOR_EMAIL=test@example.com", "GIT_COMMITTER_NAME=Test", "GIT_COMMITTER_EMAIL=test@example.com")
+		var out, err = cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("git %s failed: %v\n%s", strings.Join(args, " "), err, out)
+		}
+		return strings.TrimSpace(string(out))
+	}
+
+	os.MkdirAll(repo, 0755)
+	git("init", "-q")
+	os.WriteFile(filepath.Join(repo, "hello.txt"), []byte("hello from the repository\n"), 0644)
+	git("add", "hello.txt")
+	git("commit", "-q", "-m", "initial")
+
+	return repo, git("rev-parse", "HEAD")
+}
+
+func buildRunner(t *testing.T, dir string) string {
+
+	var bin = filepath.Join(dir, "runner")
+	if out, err := exec.Command("go", "build", "-o", bin, ".").CombinedOutput(); err != nil {
+		t.Fatalf("Building the runner failed: %v\n%s", err, out)
+	}
+	return bin
+}
+
+func runnerCommand(t *testing.T, bin string, server *gitlabtest.Server, dir string) *exec.Cmd {
+
+	var configFile = filepath.Join(dir, "config.json")
+	var data, _ = json.Marshal(server.RunnerConfig(filepath.Join(dir, "work")))
+	if err := os.WriteFile(configFile, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	var cmd = exec.Command(bin)
+	cmd.Env = append(os.Environ(), configEnv+"="+configFile, "HOME="+dir)
+	return cmd
+}
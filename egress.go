@@ -0,0 +1,175 @@
+package main
+
+import (
+	"bufio"
+	"net"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+type EgressPolicy struct {
+	Mode  string
+	Allow []string
+}
+
+type EgressError string
+
+func checkEgress() {
+
+	var policies = map[string]EgressPolicy{"": config.Egress}
+	var users = map[string]string{"": config.User}
+	var executorNames = map[string]string{"": config.Executor}
+	for _, val := range config.Jobs {
+		var name = val.ProjectID + "/" + val.JobName
+		policies[name], users[name], executorNames[name] = config.Egress, config.User, config.Executor
+		if val.Egress.Mode != "" {
+			policies[name] = val.Egress
+		}
+		if val.User != "" {
+			users[name] = val.User
+		}
+		if val.Executor != "" {
+			executorNames[name] = val.Executor
+		}
+	}
+
+	for name, policy := range policies {
+
+		switch policy.Mode {
+		case "", "full":
+			continue
+		case "off":
+			if executorNames[name] == "sandbox" {
+				continue
+			}
+		case "allowlist":
+		default:
+			printErr("Unknown egress mode: " + policy.Mode)
+		}
+
+		if users[name] == "" {
+			printErr("Egress mode " + policy.Mode + " requires jobs to run as a dedicated User")
+		}
+		if _, err := exec.LookPath("nft"); err != nil {
+			printErr("Egress mode " + policy.Mode + " requires nftables (nft)")
+		}
+	}
+}
+
+func defineEgress(configJob *ConfigJob) EgressPolicy {
+
+	if configJob != nil && configJob.Egress.Mode != "" {
+		return configJob.Egress
+	}
+	return config.Egress
+}
+
+func (jc *JobContext) isEgressRestricted() bool {
+
+	if jc.egress.Mode == "" || jc.egress.Mode == "full" || jc.user == nil {
+		return false
+	}
+	return jc.egress.Mode != "off" || jc.executorName != "sandbox"
+}
+
+func (jc *JobContext) applyEgress() (cleanup func(), err error) {
+
+	cleanup = func() {}
+	if !jc.isEgressRestricted() {
+		return cleanup, nil
+	}
+
+	var dir string
+	if dir, err = jc.jobCgroup(); err != nil {
+		return cleanup, EgressError("Creating the job cgroup for the egress policy failed: " + err.Error())
+	}
+
+	var ipv4, ipv6 []string
+	if jc.egress.Mode == "allowlist" {
+		ipv4, ipv6 = resolveAllowlist(append(jc.egress.Allow, resolvers()...))
+	}
+
+	var path = strings.TrimPrefix(dir, "/sys/fs/cgroup/")
+	var socket = "socket cgroupv2 level " + strconv.Itoa(strings.Count(path, "/")+1) + " \"" + path + "\""
+	var rules = []string{
+		socket + " oifname \"lo\" accept",
+		socket + " ct state established,related accept",
+	}
+	if ipv4 != nil {
+		rules = append(rules, socket+" ip daddr { "+strings.Join(ipv4, ", ")+" } accept")
+	}
+	if ipv6 != nil {
+		rules = append(rules, socket+" ip6 daddr { "+strings.Join(ipv6, ", ")+" } accept")
+	}
+	rules = append(rules, socket+" counter reject")
+
+	var table = "neo_runner_job_" + jc.jobID
+	var script = "table inet " + table + " {\n\tchain output {\n\t\ttype filter hook output priority 0; policy accept;\n\t\t" +
+		strings.Join(rules, "\n\t\t") + "\n\t}\n}\n"
+
+	var cmd = exec.Command("nft", "-f", "-")
+	cmd.Stdin = strings.NewReader(script)
+	if data, err := cmd.CombinedOutput(); err != nil {
+		return cleanup, EgressError("Applying egress policy failed: " + strings.TrimSpace(string(data)))
+	}
+
+	jc.printTrace("Network egress restricted to " + jc.egress.Mode + " policy")
+	return func() {
+		exec.Command("nft", "delete", "table", "inet", table).Run()
+	}, nil
+}
+
+func resolveAllowlist(hosts []string) (ipv4, ipv6 []string) {
+
+	for _, host := range hosts {
+
+		if ip, network, err := net.ParseCIDR(host); err == nil {
+			if ip.To4() != nil {
+				ipv4 = append(ipv4, network.String())
+			} else {
+				ipv6 = append(ipv6, network.String())
+			}
+			continue
+		}
+
+		var ips, err = net.LookupIP(host)
+		if err != nil {
+			printLog("Resolving egress host " + host + " failed: " + err.Error())
+			continue
+		}
+		for _, ip := range ips {
+			if ip.To4() != nil {
+				ipv4 = append(ipv4, ip.String())
+			} else {
+				ipv6 = append(ipv6, ip.String())
+			}
+		}
+	}
+
+	return ipv4, ipv6
+}
+
+func resolvers() []string {
+
+	var f, err = os.Open("/etc/resolv.conf")
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	var list []string
+	var scanner = bufio.NewScanner(f)
+	for scanner.Scan() {
+		var fields = strings.Fields(scanner.Text())
+		if len(fields) == 2 && fields[0] == "nameserver" {
+			list = append(list, fields[1])
+		}
+	}
+	return list
+}
+
+func (err EgressError) Error() string {
+	return string(err)
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"os"
+	"sort"
+	"strings"
+)
+
+var runnerCredentialEnv = []string{"VAULT_TOKEN", "REGISTRATION_TOKEN", "RUNNER_P12_PASSWORD", "RUNNER_GIT_USERNAME", "RUNNER_GIT_PASSWORD", "NOTIFY_SOCKET"}
+
+var runnerExportedEnv = []string{"RUNNER_NAME", "RUNNER_EXECUTOR", "RUNNER_SLOT", "RUNNER_CACHE_DIR", "RUNNER_JOB_ID", "RUNNER_PROJECT_DIR"}
+
+var startupEnv = os.Environ()
+
+var jobEnvBase []string
+
+func isRunnerCredential(key string) bool {
+
+	for _, val := range runnerCredentialEnv {
+		if key == val {
+			return true
+		}
+	}
+	return false
+}
+
+func isRunnerExported(key string) bool {
+
+	for _, val := range runnerExportedEnv {
+		if key == val {
+			return true
+		}
+	}
+	return false
+}
+
+func defineJobEnv() {
+
+	jobEnvBase = nil
+	for _, val := range os.Environ() {
+		if key, _, _ := strings.Cut(val, "="); !isRunnerCredential(key) {
+			jobEnvBase = append(jobEnvBase, val)
+		}
+	}
+}
+
+func jobBaseEnv() map[string]string {
+
+	var env = map[string]string{}
+	for _, val := range jobEnvBase {
+		var key, value, _ = strings.Cut(val, "=")
+		env[key] = value
+	}
+	return env
+}
+
+func (jc *JobContext) setEnv(key, value string) {
+	jc.env[key] = value
+}
+
+func (jc *JobContext) unsetEnv(key string) {
+	delete(jc.env, key)
+}
+
+func (jc *JobContext) environ() []string {
+
+	var env []string
+	for key, val := range jc.env {
+		env = append(env, key+"="+val)
+	}
+	sort.Strings(env)
+	return env
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"os"
+	"os/exec"
+	"strconv"
+)
+
+func execLocal(args []string) {
+
+	var flags = flag.NewFlagSet("exec", flag.ExitOnError)
+	var file = flags.String("file", ".gitlab-ci.yml", "Path to the CI configuration file")
+	var shell = flags.String("shell", "sh", "Shell used to run job scripts")
+	flags.Parse(args)
+
+	if flags.NArg() != 1 {
+		printErr("Usage: runner exec [-file path] [-shell sh] <job name>")
+	}
+
+	var data, err = os.ReadFile(*file)
+	if err != nil {
+		printErr(err.Error())
+	}
+
+	var jobName = flags.Arg(0)
+	var ci = parseYAML(string(data))
+	var spec, ok = ci[jobName].(map[string]any)
+	if !ok {
+		printErr("Job " + jobName + " is not defined in " + *file)
+	}
+
+	var defaults, _ = ci["default"].(map[string]any)
+
+	var job = &Job{JobInfo: JobInfo{Name: jobName}}
+	for _, name := range []string{"before_script", "script", "after_script"} {
+
+		var script []string
+		for _, node := range []any{spec[name], defaults[name], ci[name]} {
+			if script, err = yamlScript(node); err != nil {
+				printErr("Invalid " + name + " for job " + jobName + ": " + err.Error())
+			}
+			if script != nil {
+				break
+			}
+		}
+		if script != nil {
+			var isAfter = name == "after_script"
+			var when = "on_success"
+			if isAfter {
+				when = "always"
+			}
+			job.Steps = append(job.Steps, Step{Name: name, Script: script, When: when, AllowFailure: isAfter})
+		}
+	}
+
+	job.Variables = []Variable{{Key: "CI", Value: "true"}, {Key: "CI_JOB_NAME", Value: jobName}}
+	job.Variables = append(job.Variables, yamlVariables(ci["variables"])...)
+	job.Variables = append(job.Variables, yamlVariables(spec["variables"])...)
+
+	defineJobEnv()
+	var jc = newJobContext(job, nil)
+	for _, val := range job.Variables {
+		jc.setEnv(val.Key, val.Value)
+		if val.Key == "CI_DEBUG_TRACE" {
+			jc.isDebugTrace = val.Value == "true"
+		}
+	}
+
+	config.Shell = *shell
+	if jc.projDir, err = os.Getwd(); err != nil {
+		printErr(err.Error())
+	}
+	jc.scriptDir = jc.projDir
+	jc.shell = *shell
+
+	jc.traceWriter = newTracePipeline(jc, os.Stdout)
+	jc.executor = newShellExecutor(jc)
+	err = jc.runSteps()
+	jc.traceWriter.Close()
+
+	if err == nil {
+		os.Exit(0)
+	}
+
+	if err, ok := err.(*exec.ExitError); ok {
+		os.Exit(err.ExitCode())
+	}
+
+	printErr(err.Error())
+}
+
+func yamlScript(node any) ([]string, error) {
+
+	switch node := node.(type) {
+	case nil:
+		return nil, nil
+
+	case string:
+		return []string{node}, nil
+
+	case []any:
+		var script = []string{}
+		for i, val := range node {
+			switch val := val.(type) {
+			case string:
+				script = append(script, val)
+			case []any:
+				var lines, err = yamlScript(val)
+				if err != nil {
+					return nil, err
+				}
+				script = append(script, lines...)
+			default:
+				return nil, errors.New("item " + strconv.Itoa(i+1) + " is not a string")
+			}
+		}
+		return script, nil
+	}
+
+	return nil, errors.New("script must be a string or a list of strings")
+}
+
+func yamlVariables(node any) []Variable {
+
+	var m, _ = node.(map[string]any)
+	var vars []Variable
+
+	for key, val := range m {
+		switch val := val.(type) {
+		case string:
+			vars = append(vars, Variable{Key: key, Value: val, Public: true})
+		case map[string]any:
+			var value, _ = val["value"].(string)
+			vars = append(vars, Variable{Key: key, Value: value, Public: true})
+		}
+	}
+
+	return vars
+}
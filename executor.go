@@ -0,0 +1,171 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"time"
+)
+
+type Executor interface {
+	Prepare(jc *JobContext, src Source) error
+	Run(jc *JobContext, name string, args []string, stdin []string) error
+	Cleanup(jc *JobContext)
+}
+
+type Source struct {
+	TargetName    string
+	SourceName    string
+	MergeID       string
+	PipelineID    string
+	IsMerge       bool
+	IsNewPipeline bool
+	Mirrors       []string
+}
+
+var executors = map[string]func(jc *JobContext) Executor{
+	"shell":   newShellExecutor,
+	"custom":  newCustomExecutor,
+	"sandbox": newSandboxExecutor,
+}
+
+func (jc *JobContext) defineExecutor() error {
+
+	var name = jc.executorName
+	var newExecutor = executors[name]
+	if newExecutor == nil {
+		return UnsupportedError("Executor " + name + " is not supported by this runner")
+	}
+
+	jc.executor = newExecutor(jc)
+	return nil
+}
+
+func checkExecutors() {
+
+	var names = append([]string{config.Executor}, config.Executors...)
+	for _, val := range config.Jobs {
+		names = append(names, val.Executor)
+	}
+
+	for _, name := range names {
+		if name != "" && executors[name] == nil {
+			printErr("Unknown executor: " + name)
+		}
+	}
+}
+
+type shellExecutor struct{}
+
+func newShellExecutor(jc *JobContext) Executor {
+	return shellExecutor{}
+}
+
+func (shellExecutor) Prepare(jc *JobContext, src Source) error {
+
+	if !src.IsNewPipeline {
+		return nil
+	}
+
+	jc.recoverRepo()
+	var err = jc.checkoutRepo(src)
+	if err == nil || !jc.isRepoCorrupted() {
+		return err
+	}
+
+	jc.printTrace("Cached checkout of the project is corrupted, cloning it again")
+	os.RemoveAll(jc.projDir)
+
+	return jc.checkoutRepo(src)
+}
+
+func (shellExecutor) Run(jc *JobContext, name string, args []string, stdin []string) error {
+
+	var cmd = exec.Command(name, args...)
+	cmd.Dir = jc.scriptDir
+	cmd.Env = jc.environ()
+	jc.applyUser(cmd)
+
+	return jc.runCmd(cmd, stdin)
+}
+
+func (shellExecutor) Cleanup(jc *JobContext) {}
+
+func (jc *JobContext) runCmd(cmd *exec.Cmd, stdin []string) error {
+
+	var flush = func() {}
+	if jc.terminal != nil {
+		cmd.Stdin = jc.terminal
+		cmd.Stdout = jc.terminal
+		cmd.Stderr = jc.terminal
+	} else {
+		flush = jc.splitOutput(cmd)
+	}
+
+	if stdin != nil && jc.terminal == nil {
+		var data bytes.Buffer
+		for _, val := range stdin {
+			data.WriteString(val + "\n")
+		}
+		cmd.Stdin = &data
+	}
+
+	setProcessGroup(cmd)
+	var startLimits = jc.wrapLimits(cmd)
+	if err := cmd.Start(); err != nil {
+		startLimits(0)
+		flush()
+		return err
+	}
+
+	var pid = cmd.Process.Pid
+	var done = make(chan struct{})
+	var stopped = make(chan struct{})
+	go func() {
+		defer close(stopped)
+		select {
+		case <-jc.stepCtx.Done():
+			terminate(pid, done)
+		case <-done:
+		}
+	}()
+
+	setJobPID(pid)
+	var cleanup = startLimits(pid)
+	var err = cmd.Wait()
+	close(done)
+	<-stopped
+	cleanup()
+	flush()
+
+	if jc.stepCtx.Err() != nil {
+		jc.printTrace("Terminated by runner: " + jc.terminationReason())
+	}
+
+	if cmd.ProcessState != nil {
+		jc.cpuTime += cmd.ProcessState.UserTime() + cmd.ProcessState.SystemTime()
+	}
+
+	return err
+}
+
+func terminate(pid int, done chan struct{}) {
+
+	terminateGroup(pid)
+	select {
+	case <-time.After(killTimeout()):
+	case <-done:
+	}
+	killGroup(pid)
+}
+
+func (jc *JobContext) terminationReason() string {
+
+	jc.abortMu.Lock()
+	defer jc.abortMu.Unlock()
+
+	if jc.abortErr != nil {
+		return jc.abortErr.Error()
+	}
+	return "timeout exceeded"
+}
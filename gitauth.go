@@ -0,0 +1,27 @@
+package main
+
+import "net/url"
+
+const credentialHelper = `!f() { test "$1" = get && printf 'username=%s\npassword=%s\n' "$RUNNER_GIT_USERNAME" "$RUNNER_GIT_PASSWORD"; }; f`
+
+func (jc *JobContext) defineGitCredentials() {
+
+	var repoURL, err = url.Parse(jc.job.GitInfo.RepoURL)
+	if err != nil || repoURL.User == nil || repoURL.Scheme != "http" && repoURL.Scheme != "https" {
+		return
+	}
+
+	var username = repoURL.User.Username()
+	var password, ok = repoURL.User.Password()
+	if !ok {
+		password = jc.job.Token
+	}
+
+	repoURL.User = nil
+	jc.job.GitInfo.RepoURL = repoURL.String()
+	jc.job.Variables = append(jc.job.Variables, Variable{Key: "RUNNER_GIT_PASSWORD", Value: password, Masked: true})
+
+	jc.setEnv("RUNNER_GIT_USERNAME", username)
+	jc.setEnv("RUNNER_GIT_PASSWORD", password)
+	jc.addGitConfig("credential."+repoURL.Scheme+"://"+repoURL.Host+".helper", credentialHelper)
+}
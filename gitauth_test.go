@@ -0,0 +1,30 @@
+package main
+
+import "testing"
+
+func TestDefineGitCredentials(t *testing.T) {
+
+	config = Config{}
+	var job = &Job{Token: "job-token", GitInfo: GitInfo{RepoURL: "https://gitlab-ci-token@gitlab.example.com/group/project.git"}}
+	var jc = newJobContext(job, nil)
+	jc.env = map[string]string{"GIT_CONFIG_COUNT": "1", "GIT_CONFIG_KEY_0": "core.autocrlf", "GIT_CONFIG_VALUE_0": "false"}
+	jc.defineGitCredentials()
+
+	if job.GitInfo.RepoURL != "https://gitlab.example.com/group/project.git" {
+		t.Errorf("RepoURL = %q, want the URL without credentials", job.GitInfo.RepoURL)
+	}
+
+	var want = map[string]string{
+		"RUNNER_GIT_USERNAME": "gitlab-ci-token",
+		"RUNNER_GIT_PASSWORD": "job-token",
+		"GIT_CONFIG_COUNT":    "2",
+		"GIT_CONFIG_KEY_0":    "core.autocrlf",
+		"GIT_CONFIG_KEY_1":    "credential.https://gitlab.example.com.helper",
+		"GIT_CONFIG_VALUE_1":  credentialHelper,
+	}
+	for key, val := range want {
+		if jc.env[key] != val {
+			t.Errorf("job env %s = %q, want %q", key, jc.env[key], val)
+		}
+	}
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"sort"
+	"strconv"
+)
+
+func (jc *JobContext) addGitConfig(key, value string) {
+
+	var count, _ = strconv.Atoi(jc.env["GIT_CONFIG_COUNT"])
+	jc.setEnv("GIT_CONFIG_KEY_"+strconv.Itoa(count), key)
+	jc.setEnv("GIT_CONFIG_VALUE_"+strconv.Itoa(count), value)
+	jc.setEnv("GIT_CONFIG_COUNT", strconv.Itoa(count+1))
+}
+
+func (jc *JobContext) defineGitConfig(configJob *ConfigJob) {
+
+	var values = map[string]string{}
+	for key, val := range config.GitConfig {
+		values[key] = val
+	}
+	if configJob != nil {
+		for key, val := range configJob.GitConfig {
+			values[key] = val
+		}
+	}
+
+	var keys []string
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		jc.addGitConfig(key, values[key])
+	}
+}
@@ -0,0 +1,348 @@
+package gitlabtest
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+type Job struct {
+	ID        int           `json:"id"`
+	Token     string        `json:"token"`
+	JobInfo   JobInfo       `json:"job_info"`
+	GitInfo   GitInfo       `json:"git_info"`
+	Variables []Variable    `json:"variables"`
+	Steps     []Step        `json:"steps"`
+	Artifacts []JobArtifact `json:"artifacts,omitempty"`
+	Image     *Image        `json:"image,omitempty"`
+	Services  []Service     `json:"services,omitempty"`
+	Retry     *Retry        `json:"retry,omitempty"`
+}
+
+type Retry struct {
+	Max  int      `json:"max"`
+	When []string `json:"when,omitempty"`
+}
+
+type Image struct {
+	Name       string   `json:"name"`
+	Entrypoint []string `json:"entrypoint,omitempty"`
+}
+
+type Service struct {
+	Name  string `json:"name"`
+	Alias string `json:"alias,omitempty"`
+}
+
+type JobInfo struct {
+	Stage     string `json:"stage"`
+	Name      string `json:"name"`
+	ProjectID int    `json:"project_id"`
+}
+
+type GitInfo struct {
+	RepoURL string `json:"repo_url"`
+	Sha     string `json:"sha"`
+}
+
+type Variable struct {
+	Key    string `json:"key"`
+	Value  string `json:"value"`
+	Public bool   `json:"public"`
+	Masked bool   `json:"masked"`
+}
+
+type Step struct {
+	Name   string   `json:"name"`
+	Script []string `json:"script"`
+}
+
+type JobArtifact struct {
+	Paths        []string `json:"paths"`
+	When         string   `json:"when"`
+	ArtifactType string   `json:"artifact_type"`
+}
+
+type State struct {
+	Token    string `json:"token"`
+	State    string `json:"state"`
+	Failure  string `json:"failure_reason"`
+	ExitCode int    `json:"exit_code"`
+}
+
+type Artifact struct {
+	Type   string
+	Format string
+	Name   string
+	Data   []byte
+}
+
+type Server struct {
+	*httptest.Server
+	RunnerToken string
+
+	mu        sync.Mutex
+	queue     []Job
+	traces    map[int]string
+	states    map[int]State
+	artifacts map[int][]Artifact
+	limited   int
+}
+
+func NewServer() *Server {
+
+	var s = &Server{
+		RunnerToken: "test-runner-token",
+		traces:      map[int]string{},
+		states:      map[int]State{},
+		artifacts:   map[int][]Artifact{},
+	}
+
+	var mux = http.NewServeMux()
+	mux.HandleFunc("/api/v4/runners", s.register)
+	mux.HandleFunc("/api/v4/runners/verify", s.verify)
+	mux.HandleFunc("/api/v4/version", s.version)
+	mux.HandleFunc("/api/v4/jobs/request", s.request)
+	mux.HandleFunc("/api/v4/jobs/", s.job)
+
+	s.Server = httptest.NewServer(mux)
+	return s
+}
+
+func (s *Server) AddJob(job Job) {
+
+	s.mu.Lock()
+	if job.Token == "" {
+		job.Token = "job-token-" + strconv.Itoa(job.ID)
+	}
+	s.queue = append(s.queue, job)
+	s.mu.Unlock()
+}
+
+func (s *Server) Trace(id int) string {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.traces[id]
+}
+
+func (s *Server) Artifacts(id int) []Artifact {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.artifacts[id]
+}
+
+func (s *Server) RateLimit(requests int) {
+
+	s.mu.Lock()
+	s.limited = requests
+	s.mu.Unlock()
+}
+
+func (s *Server) Cancel(id int) {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.states[id] = State{State: "canceled"}
+}
+
+func (s *Server) State(id int) (State, bool) {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var state, ok = s.states[id]
+	return state, ok
+}
+
+func (s *Server) Pending() int {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return len(s.queue)
+}
+
+func (s *Server) RunnerConfig(workDir string) map[string]any {
+	return map[string]any{
+		"URL":               s.URL,
+		"Token":             s.RunnerToken,
+		"ConnectionTimeout": 10,
+		"Shell":             "sh",
+		"WorkDir":           workDir,
+	}
+}
+
+func (s *Server) register(w http.ResponseWriter, r *http.Request) {
+
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]string{"token": s.RunnerToken})
+}
+
+func (s *Server) verify(w http.ResponseWriter, r *http.Request) {
+
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	if r.FormValue("token") != s.RunnerToken {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func (s *Server) version(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusUnauthorized)
+}
+
+func (s *Server) request(w http.ResponseWriter, r *http.Request) {
+
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	if r.FormValue("token") != s.RunnerToken {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	s.mu.Lock()
+	if s.limited > 0 {
+		s.limited--
+		s.mu.Unlock()
+		w.Header().Set("Retry-After", "1")
+		w.WriteHeader(http.StatusTooManyRequests)
+		return
+	}
+
+	if len(s.queue) == 0 {
+		s.mu.Unlock()
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	var job = s.queue[0]
+	s.queue = s.queue[1:]
+	s.states[job.ID] = State{Token: job.Token, State: "running"}
+	s.mu.Unlock()
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(job)
+}
+
+func (s *Server) job(w http.ResponseWriter, r *http.Request) {
+
+	var path = strings.TrimPrefix(r.URL.Path, "/api/v4/jobs/")
+	var isTrace = strings.HasSuffix(path, "/trace")
+	var isArtifacts = strings.HasSuffix(path, "/artifacts")
+
+	var id, err = strconv.Atoi(strings.TrimSuffix(strings.TrimSuffix(path, "/trace"), "/artifacts"))
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	if isArtifacts && r.Method == http.MethodPost {
+		s.artifact(w, r, id)
+		return
+	}
+
+	var data []byte
+	if data, err = io.ReadAll(r.Body); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if isTrace && r.Method == http.MethodPatch {
+
+		var start = contentRangeStart(r.Header.Get("Content-Range"))
+		if start > len(s.traces[id]) {
+			w.Header().Set("Range", "0-"+strconv.Itoa(len(s.traces[id])))
+			w.WriteHeader(http.StatusRequestedRangeNotSatisfiable)
+			return
+		}
+
+		s.traces[id] = s.traces[id][:start] + string(data)
+		w.Header().Set("Range", "0-"+strconv.Itoa(len(s.traces[id])))
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+
+	if !isTrace && r.Method == http.MethodPut {
+
+		if s.states[id].State == "canceled" {
+			w.Header().Set("Job-Status", "canceled")
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+
+		var state State
+		if json.Unmarshal(data, &state) != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		s.states[id] = state
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	w.WriteHeader(http.StatusMethodNotAllowed)
+}
+
+func (s *Server) artifact(w http.ResponseWriter, r *http.Request, id int) {
+
+	var f, header, err = r.FormFile("file")
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	defer f.Close()
+
+	var data []byte
+	if data, err = io.ReadAll(f); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	s.artifacts[id] = append(s.artifacts[id], Artifact{
+		Type:   r.FormValue("artifact_type"),
+		Format: r.FormValue("artifact_format"),
+		Name:   header.Filename,
+		Data:   data,
+	})
+	s.mu.Unlock()
+
+	w.WriteHeader(http.StatusCreated)
+}
+
+func contentRangeStart(header string) int {
+
+	var i = strings.IndexByte(header, '-')
+	if i <= 0 {
+		return 0
+	}
+
+	var start, _ = strconv.Atoi(header[:i])
+	return start
+}
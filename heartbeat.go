@@ -0,0 +1,36 @@
+package main
+
+import "time"
+
+const defaultHeartbeatInterval = 60
+
+func startHeartbeat(jc *JobContext) (stop func()) {
+
+	var interval = config.HeartbeatInterval
+	if interval < 0 {
+		return func() {}
+	}
+	if interval == 0 {
+		interval = defaultHeartbeatInterval
+	}
+
+	var done = make(chan struct{})
+	go func() {
+		var ticker = time.NewTicker(time.Second * interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				if isRemotelyFinished(updateJob(jc.jobID, State{Token: jc.job.Token, State: "running"})) {
+					jc.abort(CanceledError("Job was canceled or is no longer running on the server"))
+					return
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	return func() { close(done) }
+}
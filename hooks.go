@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"os"
+	"os/exec"
+)
+
+type Hooks struct {
+	PreClone   []string
+	PreScript  []string
+	PostScript []string
+	PostJob    []string
+}
+
+type HookError string
+
+func (jc *JobContext) runHook(name string, script []string) error {
+
+	if len(script) == 0 {
+		return nil
+	}
+
+	var isPost = name == "post_script" || name == "post_job"
+
+	var ctx = jc.ctx
+	if isPost {
+		ctx = context.Background()
+	}
+
+	var cancel = context.CancelFunc(func() {})
+	if timeout := jc.timeouts[name]; timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+	}
+	jc.stepCtx = ctx
+
+	var cmd = exec.Command(config.Shell)
+	cmd.Env = jc.environ()
+	cmd.Dir = jc.projDir
+	if _, err := os.Stat(cmd.Dir); err != nil {
+		cmd.Dir = config.WorkDir
+	}
+
+	var err = jc.runCmd(cmd, script)
+	cancel()
+	jc.stepCtx = jc.ctx
+
+	if err == nil {
+		return nil
+	}
+
+	if !isPost && jc.ctx.Err() != nil {
+		jc.abortMu.Lock()
+		defer jc.abortMu.Unlock()
+		return jc.abortErr
+	}
+
+	err = HookError("Hook " + name + " failed: " + err.Error())
+	if isPost {
+		jc.printTrace(err.Error())
+	}
+
+	return err
+}
+
+func (err HookError) Error() string {
+	return string(err)
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"path"
+	"strings"
+)
+
+type ImagePolicy struct {
+	Name       string
+	Entrypoint []string
+	Allowed    []string
+}
+
+func checkImages() {
+
+	var policies = []ImagePolicy{config.Image}
+	for _, val := range config.Jobs {
+		policies = append(policies, val.Image)
+	}
+
+	for _, policy := range policies {
+		for _, pattern := range policy.Allowed {
+			if _, err := path.Match(pattern, ""); err != nil {
+				printErr("Invalid allowed image pattern: " + pattern)
+			}
+		}
+	}
+}
+
+func defineImage(configJob *ConfigJob) ImagePolicy {
+
+	var policy = config.Image
+	if configJob == nil {
+		return policy
+	}
+
+	if configJob.Image.Name != "" {
+		policy.Name = configJob.Image.Name
+	}
+	if configJob.Image.Entrypoint != nil {
+		policy.Entrypoint = configJob.Image.Entrypoint
+	}
+	if configJob.Image.Allowed != nil {
+		policy.Allowed = configJob.Image.Allowed
+	}
+	return policy
+}
+
+func (jc *JobContext) defineImage(configJob *ConfigJob) error {
+
+	var policy = defineImage(configJob)
+	if policy.Name != "" {
+		if jc.job.Image.Name != "" && jc.job.Image.Name != policy.Name {
+			jc.printTrace("Using image " + policy.Name + " instead of " + jc.job.Image.Name + " requested by the job")
+		}
+		jc.job.Image.Name = policy.Name
+	}
+	if policy.Entrypoint != nil {
+		jc.job.Image.Entrypoint = policy.Entrypoint
+	}
+
+	if len(policy.Allowed) == 0 {
+		return nil
+	}
+
+	var names []string
+	if jc.job.Image.Name != "" {
+		names = append(names, jc.job.Image.Name)
+	}
+	for _, val := range jc.job.Services {
+		names = append(names, val.Name)
+	}
+
+	for _, name := range names {
+		if !isImageAllowed(name, policy.Allowed) {
+			return UnsupportedError("Image " + name + " is not allowed by this runner, allowed images are " + strings.Join(policy.Allowed, ", "))
+		}
+	}
+	return nil
+}
+
+func isImageAllowed(name string, allowed []string) bool {
+
+	for _, pattern := range allowed {
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
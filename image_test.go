@@ -0,0 +1,40 @@
+package main
+
+import (
+	"io"
+	"testing"
+)
+
+func TestDefineImage(t *testing.T) {
+
+	config = Config{Image: ImagePolicy{Allowed: []string{"registry.example.com/ci/*"}}}
+
+	var job = &Job{Image: Image{Name: "registry.example.com/ci/go:1.19"}}
+	var jc = newJobContext(job, nil)
+	if err := jc.defineImage(nil); err != nil {
+		t.Errorf("allowed image: %v", err)
+	}
+
+	job = &Job{Image: Image{Name: "docker.io/library/alpine"}}
+	jc = newJobContext(job, nil)
+	if _, ok := jc.defineImage(nil).(UnsupportedError); !ok {
+		t.Errorf("disallowed image was accepted")
+	}
+
+	job = &Job{Image: Image{Name: "registry.example.com/ci/go"}, Services: []Service{{Name: "postgres"}}}
+	jc = newJobContext(job, nil)
+	if _, ok := jc.defineImage(nil).(UnsupportedError); !ok {
+		t.Errorf("disallowed service image was accepted")
+	}
+
+	var configJob = &ConfigJob{Image: ImagePolicy{Name: "registry.example.com/ci/pinned", Entrypoint: []string{""}}}
+	job = &Job{Image: Image{Name: "docker.io/library/alpine", Entrypoint: []string{"/bin/sh"}}}
+	jc = newJobContext(job, nil)
+	jc.traceWriter = nopCloser{io.Discard}
+	if err := jc.defineImage(configJob); err != nil {
+		t.Errorf("pinned image: %v", err)
+	}
+	if job.Image.Name != "registry.example.com/ci/pinned" || len(job.Image.Entrypoint) != 1 || job.Image.Entrypoint[0] != "" {
+		t.Errorf("image = %+v, want the pinned image and entrypoint", job.Image)
+	}
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"net/url"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+var version = "dev"
+var revision = "unknown"
+
+var features = []string{"variables", "masking", "refspecs", "return_exit_code"}
+
+func requestInfo(token string) url.Values {
+
+	var executorName = config.Executor
+	if executorName == "" {
+		executorName = "shell"
+	}
+
+	var data = url.Values{
+		"token":              []string{token},
+		"info[name]":         []string{"neo-mode-runner"},
+		"info[version]":      []string{version},
+		"info[revision]":     []string{revision},
+		"info[platform]":     []string{runtime.GOOS},
+		"info[architecture]": []string{runtime.GOARCH},
+		"info[executor]":     []string{executorName},
+		"info[shell]":        []string{config.Shell},
+	}
+
+	for _, val := range features {
+		data.Set("info[features]["+val+"]", "true")
+	}
+
+	return data
+}
+
+func (jc *JobContext) printHeader(src Source) {
+
+	var hostname, _ = os.Hostname()
+	var gitVersion, _ = exec.Command("git", "--version").Output()
+	var head, _ = jc.gitOutput("rev-parse", "HEAD")
+
+	jc.printTrace("Running with neo-mode-runner " + version + " (" + revision + ") on " + hostname + " " + runtime.GOOS + "/" + runtime.GOARCH)
+	jc.printTrace("  Executor: " + jc.executorName + ", shell: " + jc.shell + ", " + strings.TrimSpace(string(gitVersion)))
+	jc.printTrace("  Project directory: " + jc.projDir)
+	if len(jc.localeKeys) > 0 {
+		jc.printTrace("  Locale: " + jc.localeSummary())
+	}
+
+	var checkout = strings.TrimSpace(head)
+	if src.IsMerge {
+		checkout += " (merge of " + src.SourceName + " into " + src.TargetName + ")"
+	} else if ref := jc.variable("CI_COMMIT_REF_NAME"); ref != "" {
+		checkout += " (" + ref + ")"
+	}
+	jc.printTrace("  Checkout: " + checkout)
+}
+
+func (jc *JobContext) printCachedSuccess(src Source, pipelineURL string) {
+
+	jc.isCached = true
+
+	var head, _ = jc.gitOutput("rev-parse", "HEAD")
+	jc.printTrace("Skipping job: it already passed on the merged result of " + src.SourceName + " into " + src.TargetName + " (" + strings.TrimSpace(head) + ")")
+	if pipelineURL != "" {
+		jc.printTrace("  Earlier run: " + pipelineURL)
+	}
+	jc.printTrace("  Push a change to the merge request or its target branch to run the job again")
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"os"
+	"sort"
+	"strconv"
+	"time"
+)
+
+type projectEntry struct {
+	path   string
+	size   int64
+	usedAt time.Time
+}
+
+func (jc *JobContext) touchProject() {
+
+	var now = time.Now()
+	os.Chtimes(jc.projDir, now, now)
+	os.Chtimes(jc.cacheDir, now, now)
+}
+
+func cleanProjects() {
+
+	if config.RetentionDays <= 0 && config.MaxDiskUsage <= 0 {
+		return
+	}
+
+	var entries = projectEntries(config.WorkDir)
+	entries = append(entries, projectEntries(config.WorkDir+"/.cache")...)
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].usedAt.Before(entries[j].usedAt)
+	})
+
+	var total int64
+	for _, val := range entries {
+		total += val.size
+	}
+
+	var deadline = time.Now().AddDate(0, 0, -config.RetentionDays)
+	var maxUsage = config.MaxDiskUsage << 20
+
+	for _, val := range entries {
+
+		var isStale = config.RetentionDays > 0 && val.usedAt.Before(deadline)
+		var isOverLimit = config.MaxDiskUsage > 0 && total > maxUsage
+		if !isStale && !isOverLimit {
+			continue
+		}
+
+		var unlock, ok = lockUnusedDir(val.path)
+		if !ok {
+			continue
+		}
+
+		var err = os.RemoveAll(val.path)
+		os.Remove(stateFile(val.path))
+		unlock()
+		if err != nil {
+			printLog("Removing " + val.path + " failed: " + err.Error())
+			continue
+		}
+
+		total -= val.size
+		printLog("Removed unused " + val.path + ", reclaimed " + strconv.FormatInt(val.size>>20, 10) + " MB")
+	}
+}
+
+func projectEntries(dir string) []projectEntry {
+
+	var list, _ = os.ReadDir(dir)
+	var entries []projectEntry
+
+	for _, val := range list {
+
+		if !val.IsDir() || val.Name()[0] == '.' || dir+"/"+val.Name() == traceLogDir() {
+			continue
+		}
+
+		var info, err = val.Info()
+		if err != nil {
+			continue
+		}
+
+		var path = dir + "/" + val.Name()
+		entries = append(entries, projectEntry{path: path, size: diskUsage(path), usedAt: info.ModTime()})
+	}
+
+	return entries
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"net/url"
+	"os"
+)
+
+func (jc *JobContext) defineJobToken() error {
+
+	if jc.job.Token == "" {
+		return nil
+	}
+
+	if jc.variable("CI_JOB_TOKEN") == "" {
+		jc.job.Variables = append(jc.job.Variables, Variable{Key: "CI_JOB_TOKEN", Value: jc.job.Token, Masked: true})
+	}
+	jc.setEnv("CI_JOB_TOKEN", jc.job.Token)
+
+	var host string
+	if base, err := parseBaseURL(config.URL); err == nil {
+		host = base.Hostname()
+	} else if repoURL, err := url.Parse(jc.job.GitInfo.RepoURL); err == nil {
+		host = repoURL.Hostname()
+	}
+	if host == "" {
+		return nil
+	}
+
+	var home = jc.tmpDir + "/home"
+	if err := os.Mkdir(home, 0700); err != nil && !os.IsExist(err) {
+		return WorkDirError("Creating directory " + home + " failed: " + err.Error())
+	}
+
+	var netrc = home + "/.netrc"
+	var data = "machine " + host + " login gitlab-ci-token password " + jc.job.Token + "\n"
+	if err := os.WriteFile(netrc, []byte(data), 0600); err != nil {
+		return WorkDirError("Writing " + netrc + " failed: " + err.Error())
+	}
+
+	if prev := jc.env["HOME"]; prev != "" {
+		if _, err := os.Stat(prev + "/.gitconfig"); err == nil {
+			var include = "[include]\n\tpath = " + prev + "/.gitconfig\n"
+			if err := os.WriteFile(home+"/.gitconfig", []byte(include), 0600); err != nil {
+				return WorkDirError("Writing " + home + "/.gitconfig failed: " + err.Error())
+			}
+		}
+	}
+
+	jc.setEnv("HOME", home)
+	jc.setEnv("NETRC", netrc)
+
+	return nil
+}
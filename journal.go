@@ -0,0 +1,87 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+const journalBackups = 3
+
+type JobResult struct {
+	ID         string    `json:"id"`
+	ProjectID  string    `json:"project_id"`
+	Name       string    `json:"name"`
+	Sha        string    `json:"sha"`
+	StartedAt  time.Time `json:"started_at"`
+	FinishedAt time.Time `json:"finished_at"`
+	Duration   float64   `json:"duration"`
+	CPUTime    float64   `json:"cpu_time"`
+	State      string    `json:"state"`
+	Failure    string    `json:"failure_reason,omitempty"`
+	ExitCode   int       `json:"exit_code"`
+	TraceBytes int       `json:"trace_bytes"`
+	Cached     bool      `json:"cached,omitempty"`
+}
+
+func (jc *JobContext) result(state State) JobResult {
+
+	var now = time.Now()
+	return JobResult{
+		ID:         jc.jobID,
+		ProjectID:  jc.projID,
+		Name:       jc.job.JobInfo.Name,
+		Sha:        jc.job.GitInfo.Sha,
+		StartedAt:  jc.startedAt,
+		FinishedAt: now,
+		Duration:   now.Sub(jc.startedAt).Seconds(),
+		CPUTime:    jc.cpuTime.Seconds(),
+		State:      state.State,
+		Failure:    state.Failure,
+		ExitCode:   state.ExitCode,
+		TraceBytes: jc.traceSize,
+		Cached:     jc.isCached,
+	}
+}
+
+func writeJournal(result JobResult) {
+
+	if config.Journal == "" {
+		return
+	}
+
+	var data, err = json.Marshal(result)
+	if err != nil {
+		return
+	}
+
+	rotateJournal()
+	if err = os.MkdirAll(filepath.Dir(config.Journal), 0755); err != nil {
+		printLog("Writing job journal failed: " + err.Error())
+		return
+	}
+
+	var f *os.File
+	if f, err = os.OpenFile(config.Journal, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644); err != nil {
+		printLog("Writing job journal failed: " + err.Error())
+		return
+	}
+
+	f.Write(append(data, '\n'))
+	f.Close()
+}
+
+func rotateJournal() {
+
+	var info, err = os.Stat(config.Journal)
+	if err != nil || config.JournalMaxSize <= 0 || info.Size() < config.JournalMaxSize<<20 {
+		return
+	}
+
+	for i := journalBackups - 1; i > 0; i-- {
+		os.Rename(config.Journal+"."+strconv.Itoa(i), config.Journal+"."+strconv.Itoa(i+1))
+	}
+	os.Rename(config.Journal, config.Journal+".1")
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultBuildsDir = "{WorkDir}/{ProjectID}{SlotSuffix}"
+	defaultCacheDir  = "{WorkDir}/.cache/{ProjectID}"
+	defaultTmpDir    = "{WorkDir}/.tmp/{ProjectID}/{Slot}"
+	defaultSlots     = 8
+)
+
+func (jc *JobContext) defineLayout() {
+
+	var slots = config.Slots
+	if slots <= 0 {
+		slots = defaultSlots
+	}
+
+	var firstDir string
+	for slot := 0; slot < slots; slot++ {
+
+		jc.expandLayout(slot)
+		if slot == 0 {
+			firstDir = jc.projDir
+		} else if jc.projDir == firstDir {
+			break
+		}
+
+		if jc.lockLayout() {
+			return
+		}
+	}
+
+	jc.expandLayout(0)
+	printLog("Waiting for " + jc.projDir + " to be released by another job")
+
+	for !jc.lockLayout() {
+		notify("WATCHDOG=1")
+		time.Sleep(time.Second)
+	}
+}
+
+func (jc *JobContext) expandLayout(slot int) {
+
+	jc.slot = slot
+	var suffix string
+	if slot > 0 {
+		suffix = "-" + strconv.Itoa(slot)
+	}
+
+	var replacer = strings.NewReplacer("{WorkDir}", config.WorkDir, "{ProjectID}", jc.projID, "{Slot}", strconv.Itoa(slot), "{SlotSuffix}", suffix)
+	var expand = func(template, def string) string {
+		if template == "" {
+			template = def
+		}
+		return replacer.Replace(template)
+	}
+
+	jc.projDir = expand(config.BuildsDir, defaultBuildsDir)
+	jc.cacheDir = expand(config.CacheDir, defaultCacheDir)
+	jc.tmpDir = expand(config.TmpDir, defaultTmpDir)
+}
+
+func (jc *JobContext) lockLayout() bool {
+
+	os.MkdirAll(filepath.Dir(jc.projDir), 0755)
+	var f, err = os.OpenFile(jc.projDir+".lock", os.O_CREATE|os.O_RDWR, 0600)
+	if err != nil {
+		return true
+	}
+
+	if lockFile(f, false) != nil {
+		f.Close()
+		return false
+	}
+
+	jc.lock = f
+	jc.lockCache()
+	return true
+}
+
+func (jc *JobContext) lockCache() {
+
+	os.MkdirAll(filepath.Dir(jc.cacheDir), 0755)
+	var f, err = os.OpenFile(jc.cacheDir+".lock", os.O_CREATE|os.O_RDWR, 0600)
+	if err != nil {
+		return
+	}
+
+	if sharedLockFile(f) != nil {
+		f.Close()
+		return
+	}
+
+	jc.cacheLock = f
+}
+
+func (jc *JobContext) releaseLayout() {
+
+	if jc.lock != nil {
+		jc.lock.Close()
+		jc.lock = nil
+	}
+	if jc.cacheLock != nil {
+		jc.cacheLock.Close()
+		jc.cacheLock = nil
+	}
+}
+
+func lockUnusedDir(dir string) (unlock func(), ok bool) {
+
+	var f, err = os.OpenFile(dir+".lock", os.O_CREATE|os.O_RDWR, 0600)
+	if err != nil {
+		return nil, false
+	}
+
+	if lockFile(f, false) != nil {
+		f.Close()
+		return nil, false
+	}
+
+	return func() { f.Close() }, true
+}
+
+func (jc *JobContext) defineScriptDir(configJob *ConfigJob) {
+
+	jc.scriptDir = jc.projDir
+	if configJob == nil || configJob.Subdir == "" {
+		return
+	}
+
+	if filepath.IsAbs(configJob.Subdir) {
+		jc.scriptDir = configJob.Subdir
+	} else {
+		jc.scriptDir = filepath.Join(jc.projDir, configJob.Subdir)
+	}
+}
+
+func (jc *JobContext) prepareLayout() error {
+
+	for _, dir := range []string{jc.cacheDir, jc.tmpDir} {
+		if err := os.MkdirAll(dir, 0700); err != nil {
+			return WorkDirError("Creating directory " + dir + " failed: " + err.Error())
+		}
+	}
+
+	jc.setEnv("RUNNER_CACHE_DIR", jc.cacheDir)
+	jc.setEnv("TMPDIR", jc.tmpDir)
+
+	return nil
+}
+
+func (jc *JobContext) cleanLayout() {
+	os.RemoveAll(jc.tmpDir)
+}
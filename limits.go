@@ -0,0 +1,25 @@
+package main
+
+type Limits struct {
+	CPUs   []int
+	Nice   int
+	Memory int64
+}
+
+func (jc *JobContext) defineLimits(configJob *ConfigJob) {
+
+	jc.limits = config.Limits
+	if configJob == nil {
+		return
+	}
+
+	if configJob.Limits.CPUs != nil {
+		jc.limits.CPUs = configJob.Limits.CPUs
+	}
+	if configJob.Limits.Nice != 0 {
+		jc.limits.Nice = configJob.Limits.Nice
+	}
+	if configJob.Limits.Memory != 0 {
+		jc.limits.Memory = configJob.Limits.Memory
+	}
+}
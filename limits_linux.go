@@ -0,0 +1,182 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"unsafe"
+)
+
+const runnerCgroup = "runner"
+
+var cgroupOnce sync.Once
+var cgroupParent string
+var cgroupErr error
+
+func (jc *JobContext) wrapLimits(cmd *exec.Cmd) (start func(pid int) (cleanup func())) {
+
+	if jc.limits.CPUs == nil && jc.limits.Nice == 0 && !jc.isCgroupNeeded() || cmd.Err != nil {
+		return func(int) func() { return func() {} }
+	}
+
+	var r, w, err = os.Pipe()
+	if err != nil {
+		return func(pid int) func() {
+			if pid == 0 {
+				return func() {}
+			}
+			var cleanup, _ = jc.applyLimits(pid)
+			return cleanup
+		}
+	}
+
+	var fd = strconv.Itoa(3 + len(cmd.ExtraFiles))
+	var script = "read -r _ <&" + fd + " || exit 125; exec " + fd + "<&-; exec \"$@\""
+	cmd.Args = append([]string{"sh", "-c", script, "sh", cmd.Path}, cmd.Args[1:]...)
+	cmd.Path = "/bin/sh"
+	cmd.ExtraFiles = append(cmd.ExtraFiles, r)
+
+	return func(pid int) func() {
+
+		r.Close()
+		defer w.Close()
+
+		if pid == 0 {
+			return func() {}
+		}
+
+		var cleanup, ok = jc.applyLimits(pid)
+		if ok {
+			w.Write([]byte("\n"))
+		}
+		return cleanup
+	}
+}
+
+func (jc *JobContext) applyLimits(pid int) (cleanup func(), ok bool) {
+
+	var warnings []string
+	if jc.limits.Nice != 0 {
+		if err := syscall.Setpriority(syscall.PRIO_PROCESS, pid, jc.limits.Nice); err != nil {
+			warnings = append(warnings, "Setting nice level failed: "+err.Error())
+		}
+	}
+
+	if jc.limits.CPUs != nil {
+		var mask [16]uint64
+		for _, cpu := range jc.limits.CPUs {
+			if cpu >= 0 && cpu < len(mask)*64 {
+				mask[cpu/64] |= 1 << (cpu % 64)
+			}
+		}
+
+		var _, _, errno = syscall.RawSyscall(syscall.SYS_SCHED_SETAFFINITY, uintptr(pid), unsafe.Sizeof(mask), uintptr(unsafe.Pointer(&mask[0])))
+		if errno != 0 {
+			warnings = append(warnings, "Setting CPU affinity failed: "+errno.Error())
+		}
+	}
+
+	ok = true
+	if jc.isCgroupNeeded() {
+		var dir, err = jc.jobCgroup()
+		if err == nil && jc.limits.Memory > 0 {
+			var memory = strconv.FormatInt(jc.limits.Memory<<20, 10)
+			if err := os.WriteFile(dir+"/memory.max", []byte(memory), 0644); err != nil {
+				warnings = append(warnings, "Setting memory limit failed: "+err.Error())
+			}
+		}
+		if err == nil {
+			err = os.WriteFile(dir+"/cgroup.procs", []byte(strconv.Itoa(pid)), 0644)
+		}
+		if err != nil {
+			warnings = append(warnings, "Moving job into its cgroup failed: "+err.Error())
+			ok = !jc.isEgressRestricted()
+		}
+	}
+
+	return func() {
+		for _, val := range warnings {
+			jc.printTrace(val)
+		}
+	}, ok
+}
+
+func (jc *JobContext) isCgroupNeeded() bool {
+	return jc.limits.Memory > 0 || jc.isEgressRestricted()
+}
+
+func (jc *JobContext) jobCgroup() (string, error) {
+
+	if jc.cgroup != "" {
+		return jc.cgroup, nil
+	}
+
+	cgroupOnce.Do(func() {
+		cgroupParent, cgroupErr = delegateCgroup()
+	})
+	if cgroupErr != nil {
+		return "", cgroupErr
+	}
+
+	var dir = cgroupParent + "/job-" + jc.jobID
+	if err := os.Mkdir(dir, 0755); err != nil && !os.IsExist(err) {
+		return "", err
+	}
+
+	jc.cgroup = dir
+	return dir, nil
+}
+
+func (jc *JobContext) releaseCgroup() {
+
+	if jc.cgroup != "" {
+		os.Remove(jc.cgroup)
+		jc.cgroup = ""
+	}
+}
+
+func delegateCgroup() (string, error) {
+
+	var data, err = os.ReadFile("/proc/self/cgroup")
+	if err != nil {
+		return "", err
+	}
+
+	var base string
+	for _, line := range strings.Split(string(data), "\n") {
+		if strings.HasPrefix(line, "0::") {
+			base = "/sys/fs/cgroup" + strings.TrimSuffix(line[3:], "/")
+		}
+	}
+	if base == "" {
+		return "", os.ErrNotExist
+	}
+	if filepath.Base(base) == runnerCgroup {
+		base = filepath.Dir(base)
+	}
+
+	var controllers []byte
+	if controllers, err = os.ReadFile(base + "/cgroup.controllers"); err != nil {
+		return "", errors.New("cgroup v2 is not available in " + base)
+	}
+
+	var leaf = base + "/" + runnerCgroup
+	if err = os.Mkdir(leaf, 0755); err != nil && !os.IsExist(err) {
+		return "", err
+	}
+	if err = os.WriteFile(leaf+"/cgroup.procs", []byte(strconv.Itoa(os.Getpid())), 0644); err != nil {
+		return "", err
+	}
+	if strings.Contains(" "+strings.TrimSpace(string(controllers))+" ", " memory ") {
+		if err = os.WriteFile(base+"/cgroup.subtree_control", []byte("+memory"), 0644); err != nil {
+			return "", err
+		}
+	}
+
+	return base, nil
+}
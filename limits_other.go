@@ -0,0 +1,25 @@
+//go:build !linux
+
+package main
+
+import (
+	"errors"
+	"os/exec"
+)
+
+func (jc *JobContext) wrapLimits(cmd *exec.Cmd) (start func(pid int) (cleanup func())) {
+
+	return func(pid int) func() {
+		return func() {
+			if pid != 0 && (jc.limits.CPUs != nil || jc.limits.Nice != 0 || jc.limits.Memory > 0) {
+				jc.printTrace("Resource limits are not supported on this platform")
+			}
+		}
+	}
+}
+
+func (jc *JobContext) jobCgroup() (string, error) {
+	return "", errors.New("cgroups are not supported on this platform")
+}
+
+func (jc *JobContext) releaseCgroup() {}
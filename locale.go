@@ -0,0 +1,70 @@
+package main
+
+import (
+	"sort"
+	"strings"
+	"time"
+)
+
+func isLocaleKey(key string) bool {
+	return key == "TZ" || key == "LANG" || key == "LANGUAGE" || strings.HasPrefix(key, "LC_")
+}
+
+func checkLocale() {
+
+	var locales = []map[string]string{config.Locale}
+	for _, val := range config.Jobs {
+		locales = append(locales, val.Locale)
+	}
+
+	for _, locale := range locales {
+		for key, val := range locale {
+			if !isLocaleKey(key) {
+				printErr("Invalid locale variable " + key + ", only TZ, LANG, LANGUAGE and LC_* can be pinned")
+			}
+			if key == "TZ" {
+				if _, err := time.LoadLocation(strings.TrimPrefix(val, ":")); err != nil {
+					printErr("Invalid time zone " + val + ": " + err.Error())
+				}
+			}
+		}
+	}
+}
+
+func (jc *JobContext) defineLocale(configJob *ConfigJob) {
+
+	var values = map[string]string{}
+	for key, val := range config.Locale {
+		values[key] = val
+	}
+	if configJob != nil {
+		for key, val := range configJob.Locale {
+			values[key] = val
+		}
+	}
+
+	jc.localeKeys = nil
+	if len(values) == 0 {
+		return
+	}
+
+	for key := range jc.env {
+		if isLocaleKey(key) {
+			jc.unsetEnv(key)
+		}
+	}
+	for key, val := range values {
+		jc.setEnv(key, val)
+		jc.localeKeys = append(jc.localeKeys, key)
+	}
+	sort.Strings(jc.localeKeys)
+}
+
+func (jc *JobContext) localeSummary() string {
+
+	var pairs []string
+	for _, key := range jc.localeKeys {
+		pairs = append(pairs, key+"="+jc.env[key])
+	}
+	return strings.Join(pairs, ", ")
+}
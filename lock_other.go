@@ -0,0 +1,13 @@
+//go:build !unix
+
+package main
+
+import "os"
+
+func lockFile(f *os.File, wait bool) error {
+	return nil
+}
+
+func sharedLockFile(f *os.File) error {
+	return nil
+}
@@ -2,37 +2,143 @@ package main
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
-	"fmt"
-	"net/http"
-	"net/url"
+	"io"
+	"net"
 	"os"
 	"os/exec"
+	"sync"
 	"time"
 
 	"github.com/neo-mode/runner-api"
 )
 
 type Config struct {
-	Token             string
+	URL               string
+	Name              string
+	Token             string `json:",omitempty"`
+	TokenCommand      []string
+	TokenStoreCommand []string
+	Tokens            []string
+	TokenWeights      []int
+	Registration      Registration
 	ConnectionTimeout time.Duration
-
-	Shell   string
-	WorkDir string
-
-	Protection   bool
-	CacheSucceed bool
+	HeartbeatInterval time.Duration
+	Debug             bool
+
+	Proxy              string
+	CAFile             string
+	InsecureSkipVerify bool
+	ClientCert         string
+	ClientKey          string
+	ClientP12          string
+	ClientP12Password  string
+
+	Shell     string
+	User      string
+	WorkDir   string
+	BuildsDir string
+	CacheDir  string
+	TmpDir    string
+	Executor  string
+	Executors []string
+	Custom    CustomConfig
+	Sandbox   SandboxConfig
+	Egress    EgressPolicy
+	Image     ImagePolicy
+
+	CacheStorage ObjectStorage
+	StateStorage ObjectStorage
+
+	Protection         bool
+	CacheSucceed       bool
+	SkipMerge          bool
+	MergeName          string
+	MergeEmail         string
+	MergeMessage       string
+	MergeRetentionDays int
+	Mirrors            []string
+	Capacity           string
+	Slots              int
+	DryRun             bool
+
+	Maintenance []MaintenanceWindow
+
+	PrepareRetries     int
+	RequeuePreparation bool
+	Retry              RetryPolicy
+
+	Trace          []string
+	TraceLimit     int
+	TraceSpool     bool
+	TraceChunkSize int
+	Timestamps     string
+	Stderr         string
+	StderrArtifact bool
+
+	TraceLogs             bool
+	TraceLogRetentionDays int
+
+	DebugSnapshot         bool
+	DebugSnapshotLines    int
+	DebugSnapshotExpireIn string
+
+	CoverageRegex string
+	DebugTerminal int
+	StepTimeouts  map[string]string
+	KillTimeout   string
+	ControlSocket string
+
+	DiskQuota     int64
+	MinFreeSpace  int64
+	RetentionDays int
+	MaxDiskUsage  int64
+	MinutesQuotas map[string]MinutesQuota
+
+	Journal        string
+	JournalMaxSize int64
+	Webhooks       []Webhook
+
+	Limits Limits
+
+	Variables  map[string]string
+	GitConfig  map[string]string
+	Locale     map[string]string
+	Secrets    map[string]Secret
+	Vault      VaultConfig
+	PreScript  []string
+	PostScript []string
+	Hooks      Hooks
 
 	Jobs []ConfigJob
+
+	Runners []json.RawMessage
 }
 
 type ConfigJob struct {
 	ProjectID string
 	JobName   string
 
-	Cmd   string
-	Args  []string
-	Stdin []string
+	Cmd          string
+	Args         []string
+	Stdin        []string
+	Capacity     string
+	Executor     string
+	SkipMerge    bool
+	Mirrors      []string
+	Subdir       string
+	Shell        string
+	User         string
+	Egress       EgressPolicy
+	Image        ImagePolicy
+	Limits       Limits
+	Variables    map[string]string
+	GitConfig    map[string]string
+	Locale       map[string]string
+	Secrets      map[string]Secret
+	StepTimeouts map[string]string
+	Retry        RetryPolicy
 }
 
 type Job struct {
@@ -42,6 +148,22 @@ type Job struct {
 	GitInfo   GitInfo `json:"git_info"`
 	Variables []Variable
 	Steps     []Step
+	Artifacts []Artifact
+	Image     Image
+	Services  []Service
+	Retry     RetryPolicy
+}
+
+type Image struct {
+	Name       string
+	Entrypoint []string
+}
+
+type Service struct {
+	Name       string
+	Alias      string
+	Entrypoint []string
+	Command    []string
 }
 
 type JobInfo struct {
@@ -59,70 +181,183 @@ type Variable struct {
 	Key    string
 	Value  string
 	Public bool
+	Masked bool
 }
 
 type Step struct {
-	Name   string
-	Script []string
+	Name         string
+	Script       []string
+	When         string
+	AllowFailure bool `json:"allow_failure"`
 }
 
+type UnsupportedError string
+
+type ProtectionError string
+
 type State struct {
-	Token    string `json:"token"`
-	State    string `json:"state,omitempty"`
-	Failure  string `json:"failure_reason,omitempty"`
-	ExitCode int    `json:"exit_code,omitempty"`
+	Token    string  `json:"token"`
+	State    string  `json:"state,omitempty"`
+	Failure  string  `json:"failure_reason,omitempty"`
+	ExitCode int     `json:"exit_code,omitempty"`
+	Coverage float64 `json:"coverage,omitempty"`
 }
 
 var config Config
+var configFile string
+var capacityClasses = []string{"small", "medium", "large"}
+
+type JobContext struct {
+	job          *Job
+	jobID        string
+	projID       string
+	projDir      string
+	cacheDir     string
+	tmpDir       string
+	slot         int
+	isDebugTrace bool
+	executorName string
+	scriptDir    string
+	shell        string
+
+	trace       *bytes.Buffer
+	spool       *os.File
+	traceWriter io.WriteCloser
+	traceSize   int
+	traceMu     sync.Mutex
+	traceLog    *os.File
+	snapshot    *snapshotWriter
+	stderrLog   *os.File
+	cacheLock   *os.File
+	coverage    float64
+
+	ctx      context.Context
+	stepCtx  context.Context
+	cancel   context.CancelFunc
+	abortErr error
+	abortMu  sync.Mutex
+	executor Executor
+	limits   Limits
+	cgroup   string
+	egress   EgressPolicy
+	timeouts map[string]time.Duration
+	state    ProjectState
+	lock     *os.File
+	user     *jobUser
+	terminal net.Conn
+
+	env        map[string]string
+	localeKeys []string
+
+	startedAt       time.Time
+	isScriptStarted bool
+	isCached        bool
+	cpuTime         time.Duration
+}
+
+func main() {
 
-var projID string
-var projDir string
-var pipelineID string
-var target string
-var isMergeDone bool
+	var args = parseGlobalFlags(os.Args[1:])
 
-var job *Job
-var trace *bytes.Buffer
+	if len(args) > 0 && args[0] == "exec" {
+		execLocal(args[1:])
+	}
 
-func main() {
+	defineConfigFile()
+	if len(args) > 0 && (args[0] == "install" || args[0] == "uninstall" || args[0] == "start" || args[0] == "stop") {
+		serviceCommand(args[0], args[1:])
+	}
 
-	var homeDir = os.Getenv("HOME")
-	if homeDir == "" {
-		return
+	if len(args) > 0 && args[0] == "register" {
+		register(args[1:])
 	}
+	defineConfig()
 
-	defineConfig(homeDir)
-	var err error
-	if err = os.MkdirAll(config.WorkDir, 0755); err != nil {
-		printErr(err.Error())
+	if len(args) > 0 && args[0] == "doctor" {
+		doctor()
 	}
 
+	if len(args) > 0 && args[0] == "stats" {
+		printStats()
+	}
+
+	if len(args) > 0 && args[0] == "attach" {
+		attachDebugTerminal(args[1:])
+	}
+
+	if len(args) > 0 && (args[0] == "pause" || args[0] == "resume") {
+		controlCommand(args[0])
+	}
+
+	prepareWorkDir()
+	handleSignals()
+	serveControl()
+	notify("READY=1")
+
+	var err error
 	var found bool
-	var jobID string
 	var state State
+	var trace = new(bytes.Buffer)
 
-	job = new(Job)
-	trace = new(bytes.Buffer)
+	for !isStopping.Load() {
 
-	for {
-		found, err = runner.Request(url.Values{"info[features][refspecs]": []string{"true"}, "info[features][return_exit_code]": []string{"true"}, "token": []string{config.Token}}, job)
+		notify("WATCHDOG=1")
+		if isPaused.Load() || inMaintenance() {
+			time.Sleep(time.Second)
+			continue
+		}
+
+		var job = new(Job)
+		waitRateLimit()
+		found, err = requestJob(job)
+		if isTokenRevoked(err) {
+			reregister()
+			continue
+		}
+		if isRateLimited(err) {
+			continue
+		}
 		if err != nil {
 			printErr(err.Error())
 		}
+		if !found && runnerIndex+1 < len(runnerConfigs) {
+			useRunner(runnerIndex + 1)
+			prepareWorkDir()
+			continue
+		}
 		if !found {
 			break
 		}
 
-		jobID = string(job.ID)
-		projID = string(job.JobInfo.ProjectID)
-		projDir = config.WorkDir + "/" + projID
+		var jc = newJobContext(job, trace)
+		jc.defineLayout()
 
 		state.Token = job.Token
 		state.State = "success"
 		state.ExitCode = 0
 		state.Failure = ""
 
-		if err = handleJob(); err != nil {
+		var sink io.Writer = trace
+		if config.TraceSpool && jc.createSpool() == nil {
+			sink = jc.spool
+		}
+
+		if jc.createTraceLog(); jc.traceLog != nil {
+			sink = io.MultiWriter(sink, jc.traceLog)
+		}
+
+		jc.createStderrLog()
+		if jc.createSnapshot(); jc.snapshot != nil {
+			sink = io.MultiWriter(sink, jc.snapshot)
+		}
+
+		jc.traceWriter = newTracePipeline(jc, syncWriter{&jc.traceMu, sink})
+		startJobStatus(jc)
+		var stopTraceUpload = jc.startTraceUpload()
+		var stopKeepAlive = keepAlive()
+		var stopHeartbeat = startHeartbeat(jc)
+
+		if err = jc.runJob(); err != nil {
 			state.State = "failed"
 
 			switch err := err.(type) {
@@ -130,43 +365,134 @@ func main() {
 				state.ExitCode = err.ExitCode()
 				state.Failure = "script_failure"
 
-			case runner.APIError:
+			case runner.APIError, ProtectionError:
 				state.Failure = "api_failure"
 
+			case UnsupportedError:
+				state.Failure = "runner_unsupported"
+				jc.printTrace(err.Error())
+
+			case QuotaError:
+				state.Failure = "ci_quota_exceeded"
+				jc.printTrace(err.Error())
+
+			case MinutesQuotaError:
+				state.Failure = "ci_quota_exceeded"
+				jc.printTrace(err.Error())
+
+			case StepTimeoutError:
+				state.Failure = "job_execution_timeout"
+				jc.printTrace(err.Error())
+
+			case FetchError, CheckoutError, SecretError:
+				state.Failure = "unmet_prerequisites"
+				jc.printTrace(err.Error())
+
+			case MergeConflictError:
+				state.Failure = "unmet_prerequisites"
+				jc.printTrace(err.Error())
+
+			case MissingRefError:
+				state.Failure = "data_integrity_failure"
+				jc.printTrace(err.Error())
+
+			case WorkDirError, DiskSpaceError, CanceledError, HookError, UserError, EgressError:
+				state.Failure = "runner_system_failure"
+				jc.printTrace(err.Error())
+
 			default:
 				state.Failure = "runner_system_failure"
 			}
+
+			if config.RequeuePreparation && !jc.isScriptStarted && isPreparationError(err) {
+				state.Failure = "runner_system_failure"
+			}
 		}
 
-		runner.SendTrace(jobID, job.Token, trace)
-		runner.Update(jobID, state)
+		if state.Failure == "" || state.Failure == "script_failure" {
+			jc.uploadReports(err == nil)
+		}
 
-		trace.Reset()
+		if err != nil {
+			jc.uploadSnapshot()
+		}
+		jc.uploadStderrLog()
+
+		stopKeepAlive()
+		stopHeartbeat()
+		jc.cancel()
+		jc.touchProject()
+		jc.releaseLayout()
+		jc.traceWriter.Close()
+		jc.closeTraceLog()
+		state.Coverage = jc.coverage
+
+		stopTraceUpload()
+		jc.sendTrace()
+		if err := sendUpdate(jc.jobID, state); err != nil {
+			printLog("Updating state of job " + jc.jobID + " failed: " + err.Error())
+		}
+		finishJobStatus(state)
+		var result = jc.result(state)
+		writeJournal(result)
+		recordUsage(result)
+		fireWebhooks(result)
+
+		cleanProjects()
+		cleanTraceLogs()
 		time.Sleep(time.Second)
 	}
+
+	if isUpgrading.Load() {
+		reexec()
+	}
+
+	notify("STOPPING=1")
 }
 
-func handleJob() error {
+func (jc *JobContext) handleJob() error {
 
 	var configJob *ConfigJob
-	var jobName = job.JobInfo.Name
+	var jobName = jc.job.JobInfo.Name
 
 	for _, val := range config.Jobs {
-		if (val.ProjectID == "" || val.ProjectID == projID) && val.JobName == jobName {
+		if (val.ProjectID == "" || val.ProjectID == jc.projID) && val.JobName == jobName {
 			configJob = &val
 			break
 		}
 	}
 
 	if config.Protection && configJob == nil {
-		return runner.APIError("")
+		return ProtectionError("Job " + jobName + " has no entry in Jobs and protection is enabled")
+	}
+
+	if jc.job.GitInfo.Sha == "" || jc.job.GitInfo.RepoURL == "" {
+		return UnsupportedError("Job payload has no git_info, this GitLab version is not supported by the runner")
+	}
+
+	if configJob == nil && jc.job.Steps == nil {
+		return UnsupportedError("Job payload has no steps, this GitLab version is not supported by the runner")
+	}
+
+	if err := jc.defineImage(configJob); err != nil {
+		return err
+	}
+
+	if err := jc.selectExecutor(configJob); err != nil {
+		return err
+	}
+
+	var targetName, sourceName, mergeID, _pipelineID, sizeHint string
+
+	jc.defineLocale(configJob)
+	for key, val := range config.Variables {
+		jc.setEnv(key, val)
 	}
 
-	var targetName, sourceName, mergeID, _pipelineID string
-	for _, val := range job.Variables {
+	for _, val := range jc.job.Variables {
 
 		if val.Public {
-			os.Setenv(val.Key, val.Value)
+			jc.setEnv(val.Key, val.Value)
 		}
 
 		if val.Key == "CI_MERGE_REQUEST_TARGET_BRANCH_NAME" {
@@ -180,180 +506,303 @@ func handleJob() error {
 
 		} else if val.Key == "CI_PIPELINE_IID" {
 			_pipelineID = val.Value
+
+		} else if val.Key == "CI_DEBUG_TRACE" {
+			jc.isDebugTrace = val.Value == "true"
+
+		} else if val.Key == "CI_JOB_SIZE" {
+			sizeHint = val.Value
 		}
 	}
 
-	var err error
-	var isMerge = targetName != "" && sourceName != ""
-	var isNewPipeline = pipelineID != _pipelineID
-	var refDir = "refs/merged/" + targetName
+	if configJob != nil {
+		for key, val := range configJob.Variables {
+			jc.setEnv(key, val)
+		}
+	}
 
-	if isNewPipeline {
+	var capacity = config.Capacity
+	if configJob != nil && configJob.Capacity != "" {
+		capacity = configJob.Capacity
+	}
 
-		var info = job.GitInfo
-		var isTargetUpdated bool
-		if isTargetUpdated, err = runner.UpdateRefs(projDir, targetName, sourceName, info.Sha, info.RepoURL); err != nil {
-			return err
-		}
+	if !fitsCapacity(sizeHint, capacity) {
+		return UnsupportedError("Job requires a " + sizeHint + " runner, but this runner only provides " + capacity + " capacity")
+	}
 
-		var source string
-		if isMerge {
-			if isTargetUpdated {
-				os.RemoveAll(projDir + "/.git/" + refDir)
-			} else {
-				target = runner.GetRef(projDir, refDir+"/"+mergeID)
-			}
-			if target == "" {
-				target = "origin/" + targetName
-			}
-			source = "origin/" + sourceName
-		} else {
-			target = info.Sha
-		}
+	var mirrors = config.Mirrors
+	if configJob != nil && len(configJob.Mirrors) > 0 {
+		mirrors = configJob.Mirrors
+	}
 
-		if isMergeDone, err = runner.Checkout(projDir, target, source); err != nil {
-			return err
-		}
+	var skipMerge = config.SkipMerge || configJob != nil && configJob.SkipMerge
+	var isMerge = targetName != "" && sourceName != "" && !skipMerge
+	var src = Source{
+		TargetName: targetName,
+		SourceName: sourceName,
+		MergeID:    mergeID,
+		PipelineID: _pipelineID,
+		IsMerge:    isMerge,
+		Mirrors:    mirrors,
+	}
 
-		pipelineID = _pipelineID
+	jc.defineScriptDir(configJob)
+	jc.defineShell(configJob)
+	if isDryRun() {
+		return jc.dryRun(configJob, src)
 	}
 
-	if isMerge && config.CacheSucceed {
-		if isMergeDone {
-			if target == runner.GetRef(projDir, refDir+"/"+mergeID+"-"+jobName) {
-				return nil
-			}
-		} else {
-			runner.SetRef(projDir, refDir, mergeID, "HEAD")
-		}
+	var err error
+	if err = jc.loadSecrets(configJob); err != nil {
+		return err
+	}
+	jc.defineGitCredentials()
+	jc.defineGitConfig(configJob)
+	if err = jc.defineUser(configJob); err != nil {
+		return err
 	}
 
-	if configJob != nil {
+	if err = jc.checkMinutesQuota(); err != nil {
+		return err
+	}
 
-		if err = execScript(configJob.Cmd, configJob.Args, configJob.Stdin); err != nil {
-			return err
-		}
+	if err = checkFreeSpace(); err != nil {
+		return err
+	}
 
-		if isMerge && config.CacheSucceed {
-			runner.SetRef(projDir, refDir, mergeID+"-"+jobName, "HEAD")
-		}
+	if err = jc.prepareLayout(); err != nil {
+		return err
+	}
+	defer jc.cleanLayout()
+	jc.restoreCache()
 
-		return nil
+	if err = jc.defineJobToken(); err != nil {
+		return err
 	}
 
-	var before, script, after []string
-	for _, val := range job.Steps {
+	jc.defineLimits(configJob)
+	defer jc.releaseCgroup()
+	jc.egress = defineEgress(configJob)
+	jc.defineStepTimeouts(configJob)
+	if err = jc.defineExecutor(); err != nil {
+		return err
+	}
+	defer jc.executor.Cleanup(jc)
+	defer jc.runHook("post_job", config.Hooks.PostJob)
 
-		if val.Name == "before_script" {
-			before = val.Script
+	if err = jc.chownLayout(); err != nil {
+		return err
+	}
 
-		} else if val.Name == "script" {
-			script = val.Script
+	jc.loadState()
+	src.IsNewPipeline = jc.state.PipelineID != _pipelineID || jc.state.Sha != jc.job.GitInfo.Sha
 
-		} else if val.Name == "after_script" {
-			after = val.Script
-		}
+	if err = jc.runHook("pre_clone", config.Hooks.PreClone); err != nil {
+		return err
 	}
 
-	if before != nil {
-		if err = execScript(config.Shell, nil, before); err != nil {
-			return err
+	if err = jc.executor.Prepare(jc, src); err != nil {
+		return err
+	}
+
+	var removeEgress func()
+	if removeEgress, err = jc.applyEgress(); err != nil {
+		return err
+	}
+	defer removeEgress()
+
+	if src.IsNewPipeline {
+		jc.state.PipelineID = _pipelineID
+		jc.state.Sha = jc.job.GitInfo.Sha
+		jc.saveState()
+	}
+
+	var digest = jc.jobDigest(configJob)
+	if isMerge && config.CacheSucceed {
+		jc.saveMerge(targetName, sourceName, mergeID)
+		if pipelineURL, ok := jc.cachedSuccess(mergeID, jobName, digest); ok {
+			jc.printCachedSuccess(src, pipelineURL)
+			return nil
 		}
 	}
 
-	err = execScript(config.Shell, nil, script)
+	var stopQuota = jc.watchDiskQuota()
+	defer stopQuota()
 
-	if after != nil {
-		execScript(config.Shell, nil, after)
+	if _, err = os.Stat(jc.scriptDir); err != nil {
+		return WorkDirError("Working directory " + jc.scriptDir + " is not available: " + err.Error())
 	}
 
+	jc.defineRunnerEnv(src)
+	jc.printHeader(src)
+
+	if err = jc.runHook("pre_script", config.Hooks.PreScript); err != nil {
+		return err
+	}
+
+	jc.isScriptStarted = true
+
+	err = jc.runScript(configJob)
+	if _, ok := err.(*exec.ExitError); ok {
+		jc.serveDebugTerminal()
+	}
+
+	jc.runHook("post_script", config.Hooks.PostScript)
 	if err != nil {
 		return err
 	}
 
+	jc.saveCache()
 	if isMerge && config.CacheSucceed {
-		runner.SetRef(projDir, refDir, mergeID+"-"+jobName, "HEAD")
+		jc.markSucceeded(mergeID, jobName, digest)
 	}
 
 	return nil
 }
 
-func execScript(name string, args []string, stdin []string) error {
+func (jc *JobContext) runSteps() error {
 
-	var cmd = exec.Command(name, args...)
-	cmd.Dir = projDir
-	cmd.Stdout = trace
-	cmd.Stderr = trace
+	var err error
+	for _, step := range jc.job.Steps {
+
+		if len(step.Script) == 0 {
+			jc.printTrace("Skipping step " + step.Name + ": step type is not supported by this runner")
+			continue
+		}
+
+		if !matchesWhen(step.When, err == nil) {
+			continue
+		}
 
-	if stdin == nil {
-		return cmd.Run()
+		var shell, args, stdin = jc.shellCommand(stepScript(step))
+		var stepErr = jc.execStep(step.Name, shell, args, stdin)
+		if stepErr != nil && step.AllowFailure {
+			jc.printTrace("Step " + step.Name + " failed, but is allowed to fail")
+			continue
+		}
+
+		if err == nil {
+			err = stepErr
+		}
 	}
 
-	var data bytes.Buffer
-	for _, val := range stdin {
-		data.WriteString(val + "\n")
+	return err
+}
+
+func stepScript(step Step) []string {
+
+	if step.Name != "script" || config.PreScript == nil && config.PostScript == nil {
+		return step.Script
 	}
+	return append(append(append([]string{}, config.PreScript...), step.Script...), config.PostScript...)
+}
+
+func (jc *JobContext) execScript(name string, args []string, stdin []string) error {
 
-	cmd.Stdin = &data
-	return cmd.Run()
+	var err = jc.executor.Run(jc, name, args, stdin)
+	if err != nil && jc.ctx.Err() != nil {
+		jc.abortMu.Lock()
+		err = jc.abortErr
+		jc.abortMu.Unlock()
+	}
+
+	return err
 }
 
-func defineConfig(homeDir string) {
+func newJobContext(job *Job, trace *bytes.Buffer) *JobContext {
 
-	var confName = homeDir + "/.ci-config.json"
-	var f, err = os.Open(confName)
-	if err == nil {
+	var jc = &JobContext{job: job, jobID: string(job.ID), projID: string(job.JobInfo.ProjectID), trace: trace, startedAt: time.Now()}
+	jc.ctx, jc.cancel = context.WithCancel(context.Background())
+	jc.stepCtx = jc.ctx
+	jc.env = jobBaseEnv()
 
-		err = json.NewDecoder(f).Decode(&config)
-		f.Close()
+	return jc
+}
 
-		if err != nil {
-			printErr(err.Error())
-		}
+func (jc *JobContext) abort(err error) {
 
-		runner.Client = &http.Client{Timeout: time.Second * config.ConnectionTimeout}
-		return
+	jc.abortMu.Lock()
+	if jc.abortErr == nil {
+		jc.abortErr = err
+		jc.cancel()
 	}
+	jc.abortMu.Unlock()
+}
 
-	if !os.IsNotExist(err) {
-		printErr(err.Error())
-	}
+func fitsCapacity(sizeHint, capacity string) bool {
 
-	var token string
-	println("Input GitLab token")
+	if sizeHint == "" || capacity == "" || sizeHint == capacity {
+		return true
+	}
 
-	var n, _ = fmt.Scanln(&token)
-	if n <= 0 {
-		printErr("Cancelled")
+	var hint, max = -1, -1
+	for i, val := range capacityClasses {
+		if val == sizeHint {
+			hint = i
+		}
+		if val == capacity {
+			max = i
+		}
 	}
 
-	runner.Client = &http.Client{Timeout: time.Second * 10}
-	token, err = runner.Register(url.Values{"token": []string{token}})
-	if err != nil {
-		printErr(err.Error())
+	return hint >= 0 && hint <= max
+}
+
+func (jc *JobContext) printTrace(text string) {
+	jc.traceWriter.Write([]byte(text + "\n"))
+}
+
+func (jc *JobContext) variable(key string) string {
+
+	var value string
+	for _, val := range jc.job.Variables {
+		if val.Key == key {
+			value = val.Value
+		}
 	}
 
-	config.Token = token
-	config.ConnectionTimeout = 10
-	config.WorkDir = homeDir + "/.ci"
-	config.Shell = "sh"
-	config.Jobs = []ConfigJob{{JobName: "test-job"}}
+	return value
+}
+
+func defineConfig() {
 
-	f, err = os.OpenFile(confName, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+	var err = loadConfigDocument()
+	if os.IsNotExist(err) && hasEnvRunner() {
+		configDoc, err = map[string]json.RawMessage{"Runners": json.RawMessage("[{}]")}, nil
+	}
+	if os.IsNotExist(err) {
+		register(nil)
+		err = loadConfigDocument()
+	}
 	if err != nil {
-		printErr(err.Error() + ". Registered token: " + token)
+		printErr(err.Error())
 	}
 
-	var enc = json.NewEncoder(f)
-	enc.SetIndent("", "\t")
-	enc.Encode(&config)
-	f.Close()
+	defineEnvConfig()
+	defineJobEnv()
+	defineRunners()
+	useRunner(0)
+}
+
+func printLog(text string) {
+	os.Stderr.WriteString(text + "\n")
+}
 
-	println("Runner has been registered successfully. Config path is: " + confName)
-	os.Exit(0)
+func printDebug(text string) {
+	if config.Debug {
+		printLog(text)
+	}
 }
 
 func printErr(text string) {
 	os.Stderr.WriteString(text + "\n")
 	os.Exit(1)
 }
+
+func (err UnsupportedError) Error() string {
+	return string(err)
+}
+
+func (err ProtectionError) Error() string {
+	return string(err)
+}
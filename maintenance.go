@@ -0,0 +1,150 @@
+package main
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+type MaintenanceWindow struct {
+	Schedule string
+	Duration string
+}
+
+type cronSchedule [5][]bool
+
+var cronRanges = [5][2]int{{0, 59}, {0, 23}, {1, 31}, {1, 12}, {0, 7}}
+
+var maintenanceSchedules []cronSchedule
+var maintenanceDurations []time.Duration
+var isMaintenance atomic.Bool
+
+func checkMaintenance() {
+
+	maintenanceSchedules = nil
+	maintenanceDurations = nil
+
+	for _, val := range config.Maintenance {
+
+		var schedule, err = parseCron(val.Schedule)
+		if err != nil {
+			printErr("Invalid maintenance schedule " + val.Schedule + ": " + err.Error())
+		}
+
+		var duration time.Duration
+		if duration, err = time.ParseDuration(val.Duration); err != nil || duration <= 0 {
+			printErr("Invalid duration for maintenance schedule " + val.Schedule + ": " + val.Duration)
+		}
+
+		maintenanceSchedules = append(maintenanceSchedules, schedule)
+		maintenanceDurations = append(maintenanceDurations, duration)
+	}
+}
+
+func parseCron(expr string) (cronSchedule, error) {
+
+	var schedule cronSchedule
+	var fields = strings.Fields(expr)
+	if len(fields) != 5 {
+		return schedule, errors.New("expected 5 fields (minute hour day month weekday)")
+	}
+
+	for i, field := range fields {
+
+		var min, max = cronRanges[i][0], cronRanges[i][1]
+		schedule[i] = make([]bool, max+1)
+
+		for _, part := range strings.Split(field, ",") {
+
+			var step = 1
+			if base, value, ok := strings.Cut(part, "/"); ok {
+				var err error
+				if step, err = strconv.Atoi(value); err != nil || step <= 0 {
+					return schedule, errors.New("invalid step in " + field)
+				}
+				part = base
+			}
+
+			var from, to = min, max
+			if part != "*" {
+				var first, last, isRange = strings.Cut(part, "-")
+				var err error
+				if from, err = strconv.Atoi(first); err != nil {
+					return schedule, errors.New("invalid value in " + field)
+				}
+				to = from
+				if isRange {
+					if to, err = strconv.Atoi(last); err != nil {
+						return schedule, errors.New("invalid value in " + field)
+					}
+				} else if step > 1 {
+					to = max
+				}
+			}
+
+			if from < min || to > max || from > to {
+				return schedule, errors.New("value out of range in " + field)
+			}
+			for val := from; val <= to; val += step {
+				schedule[i][val] = true
+			}
+		}
+	}
+
+	if schedule[4][7] {
+		schedule[4][0] = true
+	}
+	return schedule, nil
+}
+
+func (schedule cronSchedule) matches(t time.Time) bool {
+
+	if !schedule[0][t.Minute()] || !schedule[1][t.Hour()] || !schedule[3][int(t.Month())] {
+		return false
+	}
+
+	var isDay, isWeekday = schedule[2][t.Day()], schedule[4][int(t.Weekday())]
+	if !isAll(schedule[2][1:]) && !isAll(schedule[4]) {
+		return isDay || isWeekday
+	}
+	return isDay && isWeekday
+}
+
+func isAll(values []bool) bool {
+
+	for _, val := range values {
+		if !val {
+			return false
+		}
+	}
+	return true
+}
+
+func activeMaintenance(now time.Time) (string, bool) {
+
+	now = now.Truncate(time.Minute)
+	for i, schedule := range maintenanceSchedules {
+		for start := now; now.Sub(start) < maintenanceDurations[i]; start = start.Add(-time.Minute) {
+			if schedule.matches(start) {
+				return config.Maintenance[i].Schedule, true
+			}
+		}
+	}
+	return "", false
+}
+
+func inMaintenance() bool {
+
+	var schedule, ok = activeMaintenance(time.Now())
+	if ok != isMaintenance.Load() {
+		if ok {
+			printLog("Maintenance window " + schedule + " started, not accepting new jobs")
+		} else {
+			printLog("Maintenance window ended, accepting jobs again")
+		}
+		isMaintenance.Store(ok)
+	}
+	return ok
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseCron(t *testing.T) {
+
+	var tests = []struct {
+		expr    string
+		isValid bool
+	}{
+		{"* * * * *", true},
+		{"0 2 * * 0", true},
+		{"*/15 1-5 1,15 */2 mon", false},
+		{"*/15 1-5 1,15 */2 1-5", true},
+		{"0 0 * * 7", true},
+		{"60 * * * *", false},
+		{"* 24 * * *", false},
+		{"* * 0 * *", false},
+		{"* * * 13 *", false},
+		{"5-1 * * * *", false},
+		{"*/0 * * * *", false},
+		{"* * * *", false},
+		{"a * * * *", false},
+	}
+
+	for _, test := range tests {
+		if _, err := parseCron(test.expr); (err == nil) != test.isValid {
+			t.Errorf("parseCron(%q) error = %v, want valid %v", test.expr, err, test.isValid)
+		}
+	}
+}
+
+func TestCronMatches(t *testing.T) {
+
+	var at = func(value string) time.Time {
+		var t, _ = time.Parse("2006-01-02 15:04 Mon", value)
+		return t
+	}
+
+	var tests = []struct {
+		expr string
+		time string
+		want bool
+	}{
+		{"* * * * *", "2026-10-16 13:37 Fri", true},
+		{"30 2 * * *", "2026-10-16 02:30 Fri", true},
+		{"30 2 * * *", "2026-10-16 02:31 Fri", false},
+		{"*/15 * * * *", "2026-10-16 02:45 Fri", true},
+		{"*/15 * * * *", "2026-10-16 02:50 Fri", false},
+		{"0 0 * * 0", "2026-10-18 00:00 Sun", true},
+		{"0 0 * * 7", "2026-10-18 00:00 Sun", true},
+		{"0 0 * * 1-5", "2026-10-18 00:00 Sun", false},
+		{"0 0 1 * 5", "2026-10-16 00:00 Fri", true},
+		{"0 0 1 * 5", "2026-11-01 00:00 Sun", true},
+		{"0 0 1 * 5", "2026-10-17 00:00 Sat", false},
+		{"0 0 16 * *", "2026-10-16 00:00 Fri", true},
+		{"0 0 * 11 *", "2026-10-16 00:00 Fri", false},
+	}
+
+	for _, test := range tests {
+		var schedule, err = parseCron(test.expr)
+		if err != nil {
+			t.Fatalf("parseCron(%q) failed: %v", test.expr, err)
+		}
+		if got := schedule.matches(at(test.time)); got != test.want {
+			t.Errorf("%q matches %s = %v, want %v", test.expr, test.time, got, test.want)
+		}
+	}
+}
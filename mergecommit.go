@@ -0,0 +1,56 @@
+package main
+
+import "strings"
+
+func (jc *JobContext) mergeIdentity(source string) []string {
+
+	var env []string
+	var set = func(key, value string) {
+		if value != "" {
+			env = append(env, key+"="+value)
+		}
+	}
+
+	set("GIT_AUTHOR_NAME", config.MergeName)
+	set("GIT_COMMITTER_NAME", config.MergeName)
+	set("GIT_AUTHOR_EMAIL", config.MergeEmail)
+	set("GIT_COMMITTER_EMAIL", config.MergeEmail)
+
+	if config.MergeName != "" || config.MergeEmail != "" {
+		var date, _ = jc.gitOutput("log", "-1", "--format=%cI", source)
+		set("GIT_AUTHOR_DATE", strings.TrimSpace(date))
+		set("GIT_COMMITTER_DATE", strings.TrimSpace(date))
+	}
+
+	return env
+}
+
+func (jc *JobContext) rewriteMergeMessage(src Source, target string, identity []string) {
+
+	if config.MergeMessage == "" {
+		return
+	}
+
+	var head, _ = jc.gitOutput("rev-parse", "HEAD")
+	var base, _ = jc.gitOutput("rev-parse", target+"^{commit}")
+	if head == base {
+		return
+	}
+	if _, err := jc.gitOutput("rev-parse", "-q", "--verify", "HEAD^2"); err != nil {
+		return
+	}
+
+	var message = strings.NewReplacer(
+		"{MergeID}", src.MergeID,
+		"{PipelineID}", src.PipelineID,
+		"{Source}", src.SourceName,
+		"{Target}", src.TargetName,
+		"{Sha}", jc.job.GitInfo.Sha,
+	).Replace(config.MergeMessage)
+
+	var cmd = jc.gitCommand("commit", "--amend", "--no-verify", "-m", message)
+	cmd.Env = append(cmd.Env, identity...)
+	if err := cmd.Run(); err != nil {
+		jc.printTrace("Rewriting merge commit message failed")
+	}
+}
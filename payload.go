@@ -0,0 +1,105 @@
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+var expectedSections = []string{"id", "token", "job_info", "git_info", "variables", "steps"}
+
+func (job *Job) UnmarshalJSON(data []byte) error {
+
+	type plain Job
+	if err := json.Unmarshal(data, (*plain)(job)); err != nil {
+		return err
+	}
+
+	if !config.Debug {
+		return nil
+	}
+
+	var fields map[string]json.RawMessage
+	json.Unmarshal(data, &fields)
+
+	for _, val := range expectedSections {
+		if _, ok := fields[val]; !ok {
+			printDebug("Job payload has no " + val + " section")
+		}
+	}
+
+	var unknown = unknownFields("", data, reflect.TypeOf(plain{}))
+	sort.Strings(unknown)
+
+	for _, val := range unknown {
+		printDebug("Job payload field " + val + " is not supported by this runner")
+	}
+
+	return nil
+}
+
+func unknownFields(prefix string, data json.RawMessage, typ reflect.Type) []string {
+
+	switch typ.Kind() {
+	case reflect.Slice:
+		var items []json.RawMessage
+		if json.Unmarshal(data, &items) != nil || len(items) == 0 {
+			return nil
+		}
+
+		var seen = map[string]bool{}
+		var list []string
+		for _, item := range items {
+			for _, val := range unknownFields(strings.TrimSuffix(prefix, ".")+"[].", item, typ.Elem()) {
+				if !seen[val] {
+					seen[val] = true
+					list = append(list, val)
+				}
+			}
+		}
+		return list
+
+	case reflect.Struct:
+		var fields map[string]json.RawMessage
+		if json.Unmarshal(data, &fields) != nil {
+			return nil
+		}
+
+		var list []string
+		for key, val := range fields {
+
+			var field, ok = structField(typ, key)
+			if !ok {
+				list = append(list, prefix+key)
+				continue
+			}
+			list = append(list, unknownFields(prefix+key+".", val, field.Type)...)
+		}
+		return list
+	}
+
+	return nil
+}
+
+func structField(typ reflect.Type, key string) (reflect.StructField, bool) {
+
+	for i := 0; i < typ.NumField(); i++ {
+
+		var field = typ.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		var name = field.Name
+		if tag, _, _ := strings.Cut(field.Tag.Get("json"), ","); tag != "" {
+			name = tag
+		}
+
+		if strings.EqualFold(name, key) {
+			return field, true
+		}
+	}
+
+	return reflect.StructField{}, false
+}
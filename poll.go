@@ -0,0 +1,68 @@
+package main
+
+import "github.com/neo-mode/runner-api"
+
+var nextToken int
+
+func runnerTokens() []string {
+	return append([]string{config.Token}, config.Tokens...)
+}
+
+func tokenWeight(i int) int {
+
+	if i < len(config.TokenWeights) && config.TokenWeights[i] > 0 {
+		return config.TokenWeights[i]
+	}
+	return 1
+}
+
+func firstToken(tokens []string) int {
+
+	var slots []int
+	for i := range tokens {
+		for j := 0; j < tokenWeight(i); j++ {
+			slots = append(slots, i)
+		}
+	}
+
+	var first = slots[nextToken%len(slots)]
+	nextToken = nextToken%len(slots) + 1
+	return first
+}
+
+func requestJob(job *Job) (bool, error) {
+
+	var tokens = runnerTokens()
+	var first = firstToken(tokens)
+
+	for i := range tokens {
+
+		var index = (first + i) % len(tokens)
+		var found, err = runner.Request(requestInfo(tokens[index]), job)
+		if isTokenRevoked(err) && index > 0 {
+			printLog("An additional runner token has been rejected by GitLab, no longer polling it")
+			removeToken(tokens[index])
+			continue
+		}
+		if err != nil || found {
+			return found, err
+		}
+	}
+
+	return false, nil
+}
+
+func removeToken(token string) {
+
+	for i, val := range config.Tokens {
+		if val != token {
+			continue
+		}
+
+		config.Tokens = append(config.Tokens[:i:i], config.Tokens[i+1:]...)
+		if i+1 < len(config.TokenWeights) {
+			config.TokenWeights = append(config.TokenWeights[:i+1:i+1], config.TokenWeights[i+2:]...)
+		}
+		return
+	}
+}
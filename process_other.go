@@ -0,0 +1,28 @@
+//go:build !unix
+
+package main
+
+import (
+	"io/fs"
+	"os"
+	"os/exec"
+)
+
+func setProcessGroup(cmd *exec.Cmd) {}
+
+func terminateGroup(pid int) {
+	killGroup(pid)
+}
+
+func killGroup(pid int) {
+
+	if process, err := os.FindProcess(pid); err == nil {
+		process.Kill()
+	}
+}
+
+func setCredential(cmd *exec.Cmd, credential *userCredential) {}
+
+func fileOwner(info fs.FileInfo) (uint32, bool) {
+	return 0, false
+}
@@ -0,0 +1,41 @@
+//go:build unix
+
+package main
+
+import (
+	"io/fs"
+	"os/exec"
+	"syscall"
+)
+
+func setProcessGroup(cmd *exec.Cmd) {
+
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.Setpgid = true
+}
+
+func terminateGroup(pid int) {
+	syscall.Kill(-pid, syscall.SIGTERM)
+}
+
+func killGroup(pid int) {
+	syscall.Kill(-pid, syscall.SIGKILL)
+}
+
+func setCredential(cmd *exec.Cmd, credential *userCredential) {
+
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.Credential = &syscall.Credential{Uid: credential.Uid, Gid: credential.Gid, Groups: credential.Groups}
+}
+
+func fileOwner(info fs.FileInfo) (uint32, bool) {
+
+	if stat, ok := info.Sys().(*syscall.Stat_t); ok {
+		return stat.Uid, true
+	}
+	return 0, false
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/neo-mode/runner-api"
+)
+
+const progressInterval = 2 * time.Second
+
+type progressWriter struct {
+	next     io.Writer
+	line     []byte
+	lastSent time.Time
+}
+
+func (w *progressWriter) Write(p []byte) (int, error) {
+
+	var n = len(p)
+	for {
+		var i = bytes.IndexAny(p, "\r\n")
+		if i < 0 {
+			w.line = append(w.line, p...)
+			return n, nil
+		}
+
+		w.line = append(w.line, p[:i]...)
+		var isFinal = p[i] == '\n' || bytes.HasSuffix(w.line, []byte(", done."))
+
+		if len(w.line) > 0 && (isFinal || time.Since(w.lastSent) >= progressInterval) {
+			w.next.Write(append(w.line, '\n'))
+			w.lastSent = time.Now()
+		}
+
+		w.line = w.line[:0]
+		p = p[i+1:]
+	}
+}
+
+func (jc *JobContext) updateRefs(repoURL, targetName, sourceName string) (bool, error) {
+
+	var cmd *exec.Cmd
+	if _, err := os.Stat(jc.projDir + "/.git"); os.IsNotExist(err) {
+		if err = jc.makeUserDir(jc.projDir); err != nil {
+			return false, err
+		}
+		cmd = jc.gitCommand("clone", "--progress", repoURL, ".")
+
+	} else if targetName == "" || sourceName == "" {
+		cmd = jc.gitCommand("fetch", "--progress", repoURL, jc.job.GitInfo.Sha)
+
+	} else {
+		cmd = jc.gitCommand("fetch", "--progress", repoURL,
+			"+refs/heads/"+targetName+":refs/remotes/origin/"+targetName,
+			"+refs/heads/"+sourceName+":refs/remotes/origin/"+sourceName)
+	}
+
+	var output bytes.Buffer
+	var w = &progressWriter{next: io.MultiWriter(jc.traceWriter, &output)}
+	cmd.Stdout = w
+	cmd.Stderr = w
+
+	if err := cmd.Run(); err != nil {
+		return false, runner.GitError(err.Error())
+	}
+
+	return targetName != "" && strings.Contains(output.String(), "-> origin/"+targetName+"\n"), nil
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"io/fs"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+type QuotaError string
+
+type DiskSpaceError string
+
+const quotaInterval = time.Second * 5
+
+func (jc *JobContext) watchDiskQuota() (stop func()) {
+
+	if config.DiskQuota <= 0 {
+		return func() {}
+	}
+
+	var quota = config.DiskQuota << 20
+	var base = diskUsage(jc.projDir)
+	var done = make(chan struct{})
+
+	go func() {
+		var ticker = time.NewTicker(quotaInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				if diskUsage(jc.projDir)+diskUsage(jc.tmpDir)-base > quota {
+					jc.abort(QuotaError("Job exceeded disk quota of " + strconv.FormatInt(config.DiskQuota, 10) + " MB"))
+					return
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	return func() { close(done) }
+}
+
+func checkFreeSpace() error {
+
+	if config.MinFreeSpace <= 0 {
+		return nil
+	}
+
+	var free, err = freeSpace(config.WorkDir)
+	if err != nil {
+		return WorkDirError("Checking free space of " + config.WorkDir + " failed: " + err.Error())
+	}
+
+	if free < config.MinFreeSpace {
+		return DiskSpaceError("Not enough free disk space in " + config.WorkDir + ": " + strconv.FormatInt(free, 10) + " MB available, " + strconv.FormatInt(config.MinFreeSpace, 10) + " MB required")
+	}
+
+	return nil
+}
+
+func diskUsage(dir string) int64 {
+
+	var size int64
+	filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err == nil && entry.Type().IsRegular() {
+			if info, err := entry.Info(); err == nil {
+				size += info.Size()
+			}
+		}
+		return nil
+	})
+
+	return size
+}
+
+func (err QuotaError) Error() string {
+	return string(err)
+}
+
+func (err DiskSpaceError) Error() string {
+	return string(err)
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/neo-mode/runner-api"
+)
+
+const maxRateLimitWait = 5 * time.Minute
+const defaultRateLimitWait = 5 * time.Second
+
+var rateLimitMu sync.Mutex
+var rateLimitUntil time.Time
+
+type rateLimitTransport struct {
+	next http.RoundTripper
+}
+
+func (t *rateLimitTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+
+	var res, err = t.next.RoundTrip(req)
+	if err == nil {
+		if wait := retryAfter(res); wait > 0 {
+			limitRate(wait, req.URL.Path, res.Status)
+		}
+	}
+
+	return res, err
+}
+
+func retryAfter(res *http.Response) time.Duration {
+
+	var wait time.Duration
+	if value := res.Header.Get("Retry-After"); value != "" {
+		if seconds, err := strconv.Atoi(value); err == nil {
+			wait = time.Duration(seconds) * time.Second
+		} else if date, err := http.ParseTime(value); err == nil {
+			wait = time.Until(date)
+		}
+	}
+
+	if wait <= 0 && res.Header.Get("RateLimit-Remaining") == "0" {
+		if reset, err := strconv.ParseInt(res.Header.Get("RateLimit-Reset"), 10, 64); err == nil {
+			wait = time.Until(time.Unix(reset, 0))
+		}
+	}
+
+	if wait <= 0 && res.StatusCode == http.StatusTooManyRequests {
+		wait = defaultRateLimitWait
+	}
+
+	if wait > maxRateLimitWait {
+		wait = maxRateLimitWait
+	}
+
+	return wait
+}
+
+func limitRate(wait time.Duration, path, status string) {
+
+	rateLimitMu.Lock()
+	defer rateLimitMu.Unlock()
+
+	var until = time.Now().Add(wait)
+	if until.Before(rateLimitUntil) {
+		return
+	}
+
+	rateLimitUntil = until
+	printLog("Rate limited by GitLab on " + path + " (" + status + "), backing off for " + wait.Round(time.Second).String())
+}
+
+func waitRateLimit() {
+
+	rateLimitMu.Lock()
+	var wait = time.Until(rateLimitUntil)
+	rateLimitMu.Unlock()
+
+	if wait > 0 {
+		time.Sleep(wait)
+	}
+}
+
+func isRateLimited(err error) bool {
+
+	var apiErr, ok = err.(runner.APIError)
+	return ok && strings.HasPrefix(string(apiErr), "429")
+}
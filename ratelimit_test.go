@@ -0,0 +1,44 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestRetryAfter(t *testing.T) {
+
+	var reset = strconv.FormatInt(time.Now().Add(time.Minute).Unix(), 10)
+
+	var tests = []struct {
+		name    string
+		status  int
+		headers map[string]string
+		min     time.Duration
+		max     time.Duration
+	}{
+		{"ok", http.StatusOK, nil, 0, 0},
+		{"seconds", http.StatusTooManyRequests, map[string]string{"Retry-After": "30"}, 30 * time.Second, 30 * time.Second},
+		{"date", http.StatusServiceUnavailable, map[string]string{"Retry-After": time.Now().Add(time.Minute).UTC().Format(http.TimeFormat)}, 58 * time.Second, time.Minute},
+		{"rate limit reset", http.StatusOK, map[string]string{"RateLimit-Remaining": "0", "RateLimit-Reset": reset}, 58 * time.Second, time.Minute},
+		{"rate limit remaining", http.StatusOK, map[string]string{"RateLimit-Remaining": "10", "RateLimit-Reset": reset}, 0, 0},
+		{"default", http.StatusTooManyRequests, nil, defaultRateLimitWait, defaultRateLimitWait},
+		{"invalid", http.StatusTooManyRequests, map[string]string{"Retry-After": "soon"}, defaultRateLimitWait, defaultRateLimitWait},
+		{"capped", http.StatusTooManyRequests, map[string]string{"Retry-After": "86400"}, maxRateLimitWait, maxRateLimitWait},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+
+			var res = &http.Response{StatusCode: test.status, Header: http.Header{}}
+			for key, val := range test.headers {
+				res.Header.Set(key, val)
+			}
+
+			if got := retryAfter(res); got < test.min || got > test.max {
+				t.Errorf("retryAfter = %v, want between %v and %v", got, test.min, test.max)
+			}
+		})
+	}
+}
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/neo-mode/runner-api"
+)
+
+var stdin = bufio.NewReader(os.Stdin)
+var isNonInteractive bool
+
+type Registration struct {
+	Token       string
+	Description string
+	TagList     string
+	RunUntagged string
+	Locked      string
+}
+
+func register(args []string) {
+
+	var flags = flag.NewFlagSet("register", flag.ExitOnError)
+	var gitlabURL = flags.String("url", os.Getenv("CI_SERVER_URL"), "GitLab instance URL (CI_SERVER_URL)")
+	var token = flags.String("registration-token", os.Getenv("REGISTRATION_TOKEN"), "Runner registration token (REGISTRATION_TOKEN)")
+	var description = flags.String("description", os.Getenv("RUNNER_NAME"), "Runner description (RUNNER_NAME)")
+	var tagList = flags.String("tag-list", os.Getenv("RUNNER_TAG_LIST"), "Comma separated list of runner tags (RUNNER_TAG_LIST)")
+	flags.StringVar(tagList, "tags", *tagList, "Alias for --tag-list")
+	var runUntagged = flags.String("run-untagged", os.Getenv("REGISTER_RUN_UNTAGGED"), "Pick up jobs without tags (true/false) (REGISTER_RUN_UNTAGGED)")
+	var locked = flags.String("locked", os.Getenv("REGISTER_LOCKED"), "Lock runner to the current project (true/false) (REGISTER_LOCKED)")
+	var executor = flags.String("executor", os.Getenv("RUNNER_EXECUTOR"), "Executor for jobs (RUNNER_EXECUTOR)")
+	var shell = flags.String("shell", os.Getenv("RUNNER_SHELL"), "Shell for job scripts (RUNNER_SHELL)")
+	var workDir = flags.String("work-dir", os.Getenv("RUNNER_WORK_DIR"), "Runner work directory (RUNNER_WORK_DIR)")
+	var tokenCommand = flags.String("token-command", "", "Command printing the runner token, instead of storing it in the config")
+	var storeCommand = flags.String("token-store-command", "", "Command receiving the runner token on stdin, e.g. a keyring tool")
+	var keepRegistration = flags.Bool("keep-registration", false, "Store the registration token to register again when the runner token is revoked")
+	flags.BoolVar(&isNonInteractive, "non-interactive", os.Getenv("REGISTER_NON_INTERACTIVE") == "true", "Never prompt, use flags, environment and defaults (REGISTER_NON_INTERACTIVE)")
+	flags.Parse(args)
+
+	var statErr = loadConfigDocument()
+	var isWizard = !isNonInteractive && isInteractive() && os.IsNotExist(statErr)
+
+	if isWizard {
+		promptURL(gitlabURL)
+	} else {
+		prompt("Input GitLab URL", gitlabURL, defaultURL)
+	}
+	prompt("Input GitLab token", token, "")
+	prompt("Input runner description", description, "")
+	prompt("Input runner tags (comma separated)", tagList, "")
+	prompt("Run untagged jobs? (true/false)", runUntagged, "true")
+	prompt("Lock runner to the current project? (true/false)", locked, "false")
+
+	if *token == "" && isNonInteractive {
+		printErr("Registration token is required, pass --registration-token or set REGISTRATION_TOKEN")
+	}
+	if *token == "" {
+		printErr("Cancelled")
+	}
+
+	config.URL = *gitlabURL
+	if *description != "" {
+		config.Name = *description
+	}
+	if *executor != "" {
+		if executors[*executor] == nil {
+			printErr("Unknown executor: " + *executor)
+		}
+		config.Executor = *executor
+	}
+	if *shell != "" {
+		if _, ok := shells[*shell]; !ok {
+			printErr("Unsupported shell: " + *shell)
+		}
+		config.Shell = *shell
+	}
+	if *workDir != "" {
+		config.WorkDir = *workDir
+	}
+	if *tokenCommand != "" {
+		config.TokenCommand = strings.Fields(*tokenCommand)
+		config.TokenStoreCommand = strings.Fields(*storeCommand)
+	}
+
+	var reg = Registration{
+		Token:       *token,
+		Description: *description,
+		TagList:     *tagList,
+		RunUntagged: *runUntagged,
+		Locked:      *locked,
+	}
+	if *keepRegistration {
+		config.Registration = reg
+	}
+
+	if isWizard {
+		promptSetup()
+	}
+
+	registerRunner(reg)
+
+	if isWizard {
+		finishSetup()
+	}
+	if isNonInteractive {
+		os.Exit(0)
+	}
+}
+
+func registerRunner(reg Registration) {
+
+	if config.ConnectionTimeout == 0 {
+		config.ConnectionTimeout = 10
+	}
+	defineClient()
+
+	var data = url.Values{
+		"token":        []string{reg.Token},
+		"description":  []string{reg.Description},
+		"tag_list":     []string{reg.TagList},
+		"run_untagged": []string{reg.RunUntagged},
+		"locked":       []string{reg.Locked},
+	}
+
+	var token, err = runner.Register(data)
+	if err != nil {
+		printErr(err.Error())
+	}
+
+	config.Token = token
+	if len(config.TokenStoreCommand) > 0 {
+		if err = storeToken(config.TokenStoreCommand, token); err != nil {
+			printErr("Storing runner token failed: " + err.Error() + ". Registered token: " + token)
+		}
+	}
+
+	if config.WorkDir == "" {
+		config.WorkDir = defaultWorkDir()
+	}
+	if config.Shell == "" {
+		config.Shell = "sh"
+	}
+	if config.Jobs == nil {
+		config.Jobs = []ConfigJob{{JobName: "test-job"}}
+	}
+
+	if err = writeConfig(); err != nil {
+		printErr("Writing config failed: " + err.Error() + ". Registered token: " + token)
+	}
+
+	println("Runner has been registered successfully. Config path is: " + configFile)
+}
+
+func isTokenRevoked(err error) bool {
+
+	var apiErr, ok = err.(runner.APIError)
+	return ok && (strings.HasPrefix(string(apiErr), "401") || strings.HasPrefix(string(apiErr), "403"))
+}
+
+func reregister() {
+
+	printLog("Runner token has been rejected by GitLab, it was probably revoked")
+
+	if config.Registration.Token != "" {
+		printLog("Registering the runner again with the stored registration token")
+		registerRunner(config.Registration)
+		return
+	}
+
+	if isInteractive() {
+		register(nil)
+		return
+	}
+
+	printErr("Register the runner again with: runner register")
+}
+
+func prompt(text string, value *string, def string) {
+
+	if *value != "" {
+		return
+	}
+	if isNonInteractive {
+		*value = def
+		return
+	}
+
+	if def != "" {
+		text += " [" + def + "]"
+	}
+	println(text)
+
+	var line, _ = stdin.ReadString('\n')
+	if *value = strings.TrimSpace(line); *value == "" {
+		*value = def
+	}
+}
+
+func isInteractive() bool {
+
+	var info, err = os.Stdin.Stat()
+	if err != nil || info.Mode()&os.ModeCharDevice == 0 {
+		return false
+	}
+
+	var null, _ = os.Stat(os.DevNull)
+	return null == nil || !os.SameFile(info, null)
+}
@@ -0,0 +1,242 @@
+package main
+
+import (
+	"net/url"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/neo-mode/runner-api"
+)
+
+type FetchError string
+
+type CheckoutError string
+
+type MissingRefError string
+
+type WorkDirError string
+
+type MergeConflictError string
+
+var staleLocks = []string{"index.lock", "HEAD.lock", "config.lock", "shallow.lock", "packed-refs.lock"}
+
+func (jc *JobContext) checkoutRepo(src Source) error {
+
+	var info = jc.job.GitInfo
+	var targetName, sourceName = src.TargetName, src.SourceName
+	if !src.IsMerge {
+		targetName, sourceName = "", ""
+	}
+
+	var isTargetUpdated, isFetched = jc.fetchMirrors(src.Mirrors, targetName, sourceName)
+
+	var err error
+	if !isFetched {
+		var isUpdated bool
+		isUpdated, err = jc.updateRefs(info.RepoURL, targetName, sourceName)
+		isTargetUpdated = isTargetUpdated || isUpdated
+	}
+	if err != nil {
+		if _, ok := err.(runner.GitError); ok {
+			return FetchError("Fetching project sources from the repository failed")
+		}
+		return WorkDirError("Preparing project directory " + jc.projDir + " failed: " + err.Error())
+	}
+
+	jc.gitCmd("remote", "set-url", "origin", info.RepoURL)
+
+	var source, target string
+	var isCachedMerge bool
+	if src.IsMerge {
+		if isTargetUpdated {
+			jc.dropMerges(targetName)
+		} else if merge, ok := jc.state.Merges[src.MergeID]; ok {
+			target = merge.Result
+			merge.UsedAt = time.Now()
+			isCachedMerge = target != ""
+		}
+		if target == "" {
+			target = "origin/" + targetName
+		}
+		source = "origin/" + sourceName
+	} else {
+		target = info.Sha
+	}
+
+	jc.state.Target = target
+	var identity []string
+	if source != "" {
+		identity = jc.mergeIdentity(source)
+	}
+
+	if err = jc.checkout(target, source, identity); err == nil {
+		if src.IsMerge && !isCachedMerge {
+			jc.rewriteMergeMessage(src, target, identity)
+		}
+		return nil
+	}
+
+	if !jc.hasRef(target) {
+		return MissingRefError("Reference " + target + " does not exist in the repository")
+	}
+	if source == "" {
+		return CheckoutError("Checking out " + target + " failed")
+	}
+	if !jc.hasRef(source) {
+		return MissingRefError("Reference " + source + " does not exist in the repository")
+	}
+
+	var files, _ = jc.gitOutput("diff", "--name-only", "--diff-filter=U")
+	jc.gitCmd("merge", "--abort")
+
+	if files == "" {
+		return CheckoutError("Merging " + source + " into " + target + " failed")
+	}
+
+	jc.printTrace("Merge conflict between " + source + " and " + target + " in:")
+	for _, val := range strings.Split(strings.TrimSpace(files), "\n") {
+		jc.printTrace("  " + val)
+	}
+
+	return MergeConflictError("Merge conflict: " + source + " cannot be merged into " + target)
+}
+
+func (jc *JobContext) fetchMirrors(mirrors []string, targetName, sourceName string) (bool, bool) {
+
+	var isTargetUpdated bool
+	for _, val := range mirrors {
+
+		var name = val
+		if mirrorURL, err := url.Parse(val); err == nil {
+			mirrorURL.User = nil
+			name = mirrorURL.String()
+		}
+
+		var _, err = os.Stat(jc.projDir + "/.git")
+		var isCloned = err == nil
+
+		var isUpdated bool
+		if isUpdated, err = jc.updateRefs(val, targetName, sourceName); err != nil {
+			if !isCloned {
+				os.RemoveAll(jc.projDir)
+			}
+			jc.printTrace("Fetching from mirror " + name + " failed, trying the next source")
+			continue
+		}
+
+		isTargetUpdated = isTargetUpdated || isUpdated
+		if jc.hasRef(jc.job.GitInfo.Sha) {
+			return isTargetUpdated, true
+		}
+		jc.printTrace("Mirror " + name + " does not contain " + jc.job.GitInfo.Sha + ", trying the next source")
+	}
+
+	return isTargetUpdated, false
+}
+
+func (jc *JobContext) hasRef(ref string) bool {
+	return jc.gitCmd("rev-parse", "--verify", "--quiet", ref+"^{commit}") == nil
+}
+
+func (jc *JobContext) recoverRepo() {
+
+	if _, err := os.Stat(jc.projDir); err != nil {
+		return
+	}
+
+	if _, err := os.Stat(jc.projDir + "/.git"); err != nil {
+		jc.printTrace("Project directory has no git repository, removing it")
+		os.RemoveAll(jc.projDir)
+		return
+	}
+
+	for _, val := range staleLocks {
+		if os.Remove(jc.projDir+"/.git/"+val) == nil {
+			jc.printTrace("Removed stale git lock file " + val)
+		}
+	}
+
+	if _, err := os.Stat(jc.projDir + "/.git/MERGE_HEAD"); err == nil {
+		jc.printTrace("Aborting interrupted merge")
+		if jc.gitCmd("merge", "--abort") != nil {
+			jc.gitCmd("reset", "--hard")
+		}
+	}
+}
+
+func (jc *JobContext) isRepoCorrupted() bool {
+
+	if _, err := os.Stat(jc.projDir + "/.git"); err != nil {
+		return os.IsNotExist(err)
+	}
+
+	return jc.gitCmd("fsck", "--no-progress", "--connectivity-only") != nil
+}
+
+func (jc *JobContext) checkout(target, source string, identity []string) error {
+
+	var err = jc.gitCmd("checkout", target)
+	if source == "" || err != nil {
+		return err
+	}
+
+	var cmd = jc.gitCommand("merge", "--no-ff", source)
+	cmd.Env = append(cmd.Env, identity...)
+	return cmd.Run()
+}
+
+func (jc *JobContext) gitCommand(args ...string) *exec.Cmd {
+
+	var cmd = exec.Command("git", append([]string{"-c", "core.hooksPath=/dev/null", "-c", "core.fsmonitor=false"}, args...)...)
+	cmd.Dir = jc.projDir
+	cmd.Env = jc.gitEnv()
+
+	if jc.user != nil {
+		cmd.Env = append(cmd.Env, "HOME="+jc.user.home)
+		jc.applyUser(cmd)
+	}
+	return cmd
+}
+
+func (jc *JobContext) gitEnv() []string {
+
+	var env = append([]string{}, jobEnvBase...)
+	for key, val := range jc.env {
+		if strings.HasPrefix(key, "GIT_") || strings.HasPrefix(key, "RUNNER_GIT_") {
+			env = append(env, key+"="+val)
+		}
+	}
+	return env
+}
+
+func (jc *JobContext) gitOutput(args ...string) (string, error) {
+
+	var data, err = jc.gitCommand(args...).Output()
+	return string(data), err
+}
+
+func (jc *JobContext) gitCmd(args ...string) error {
+	return jc.gitCommand(args...).Run()
+}
+
+func (err FetchError) Error() string {
+	return string(err)
+}
+
+func (err CheckoutError) Error() string {
+	return string(err)
+}
+
+func (err MissingRefError) Error() string {
+	return string(err)
+}
+
+func (err WorkDirError) Error() string {
+	return string(err)
+}
+
+func (err MergeConflictError) Error() string {
+	return string(err)
+}
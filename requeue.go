@@ -0,0 +1,46 @@
+package main
+
+import (
+	"strconv"
+	"time"
+
+	runner "github.com/neo-mode/runner-api"
+)
+
+const prepareRetryDelay = 5 * time.Second
+
+func isPreparationError(err error) bool {
+
+	switch err.(type) {
+	case FetchError, CheckoutError, SecretError, WorkDirError, DiskSpaceError, HookError, runner.APIError:
+		return true
+	}
+
+	return false
+}
+
+func (jc *JobContext) runJob() error {
+
+	var gitInfo = jc.job.GitInfo
+	var variables = jc.job.Variables[:len(jc.job.Variables):len(jc.job.Variables)]
+
+	for attempt := 1; ; attempt++ {
+
+		var err = jc.handleJob()
+		if err == nil || jc.isScriptStarted || !isPreparationError(err) || attempt > config.PrepareRetries || jc.ctx.Err() != nil {
+			return err
+		}
+
+		jc.printTrace(err.Error())
+		jc.printTrace("Preparing the job environment failed, retrying (" + strconv.Itoa(attempt) + "/" + strconv.Itoa(config.PrepareRetries) + ")")
+
+		select {
+		case <-jc.ctx.Done():
+			return err
+		case <-time.After(prepareRetryDelay * time.Duration(attempt)):
+		}
+
+		jc.job.GitInfo = gitInfo
+		jc.job.Variables = variables
+	}
+}
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"os/exec"
+	"testing"
+
+	"github.com/neo-mode/runner-api"
+)
+
+func TestIsPreparationError(t *testing.T) {
+
+	var tests = []struct {
+		err  error
+		want bool
+	}{
+		{FetchError("fetch"), true},
+		{WorkDirError("work dir"), true},
+		{runner.APIError("502 Bad Gateway"), true},
+		{ProtectionError("protected"), false},
+		{MergeConflictError("conflict"), false},
+		{&exec.ExitError{}, false},
+	}
+
+	for _, test := range tests {
+		if got := isPreparationError(test.err); got != test.want {
+			t.Errorf("isPreparationError(%T) = %v, want %v", test.err, got, test.want)
+		}
+	}
+}
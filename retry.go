@@ -0,0 +1,96 @@
+package main
+
+import (
+	"os/exec"
+	"strconv"
+)
+
+type RetryPolicy struct {
+	Max  int
+	When []string
+}
+
+var retryReasons = map[string]bool{"always": true, "script_failure": true, "runner_system_failure": true, "job_execution_timeout": true}
+
+func checkRetry() {
+
+	var policies = []RetryPolicy{config.Retry}
+	for _, val := range config.Jobs {
+		policies = append(policies, val.Retry)
+	}
+
+	for _, policy := range policies {
+		for _, val := range policy.When {
+			if !retryReasons[val] {
+				printErr("Unknown retry condition: " + val)
+			}
+		}
+	}
+}
+
+func (jc *JobContext) defineRetry(configJob *ConfigJob) RetryPolicy {
+
+	if jc.job.Retry.Max > 0 {
+		return jc.job.Retry
+	}
+	if configJob != nil && configJob.Retry.Max > 0 {
+		return configJob.Retry
+	}
+	return config.Retry
+}
+
+func retryReason(err error) string {
+
+	switch err.(type) {
+	case QuotaError, MergeConflictError:
+		return ""
+	case *exec.ExitError:
+		return "script_failure"
+	case StepTimeoutError:
+		return "job_execution_timeout"
+	}
+	return "runner_system_failure"
+}
+
+func (policy RetryPolicy) matches(err error) bool {
+
+	var reason = retryReason(err)
+	if reason == "" {
+		return false
+	}
+	if len(policy.When) == 0 {
+		return true
+	}
+
+	for _, val := range policy.When {
+		if val == "always" || val == reason {
+			return true
+		}
+	}
+	return false
+}
+
+func (jc *JobContext) runScript(configJob *ConfigJob) error {
+
+	var policy = jc.defineRetry(configJob)
+	for attempt := 1; ; attempt++ {
+
+		if attempt > 1 {
+			jc.printTrace("")
+			jc.printTrace("=== Attempt " + strconv.Itoa(attempt) + " of " + strconv.Itoa(policy.Max+1) + " ===")
+		}
+
+		var err error
+		if configJob != nil {
+			err = jc.execStep("script", configJob.Cmd, configJob.Args, configJob.Stdin)
+		} else {
+			err = jc.runSteps()
+		}
+
+		if err == nil || attempt > policy.Max || jc.ctx.Err() != nil || !policy.matches(err) {
+			return err
+		}
+
+		jc.printTrace("Attempt " + strconv.Itoa(attempt) + " failed: " + err.Error())
+	}
+}
@@ -0,0 +1,26 @@
+package main
+
+import (
+	"os"
+	"strconv"
+	"strings"
+)
+
+func (jc *JobContext) defineRunnerEnv(src Source) {
+
+	var name = config.Name
+	if name == "" {
+		name, _ = os.Hostname()
+	}
+
+	jc.setEnv("RUNNER_NAME", name)
+	jc.setEnv("RUNNER_EXECUTOR", jc.executorName)
+	jc.setEnv("RUNNER_SLOT", strconv.Itoa(jc.slot))
+	jc.setEnv("IS_MERGED_PIPELINE", strconv.FormatBool(src.IsMerge))
+
+	if src.IsMerge {
+		if head, err := jc.gitOutput("rev-parse", "HEAD"); err == nil {
+			jc.setEnv("MERGED_RESULT_SHA", strings.TrimSpace(head))
+		}
+	}
+}
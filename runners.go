@@ -0,0 +1,167 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+var runnerKeys = []string{"URL", "Name", "Token", "TokenCommand", "TokenStoreCommand", "Tokens", "TokenWeights", "Registration", "Executor", "Shell", "WorkDir", "Jobs"}
+
+var configDoc map[string]json.RawMessage
+var runnerConfigs []Config
+var runnerIndex = -1
+
+func loadConfigDocument() error {
+
+	var data, err = os.ReadFile(configFile)
+	if err != nil {
+		return err
+	}
+
+	configDoc = nil
+	if err = json.Unmarshal(data, &configDoc); err != nil {
+		return err
+	}
+
+	if _, ok := configDoc["Runners"]; !ok {
+		return migrateConfig()
+	}
+	return nil
+}
+
+func migrateConfig() error {
+
+	var entry = map[string]json.RawMessage{}
+	for _, key := range runnerKeys {
+		if val, ok := configDoc[key]; ok {
+			entry[key] = val
+			delete(configDoc, key)
+		}
+	}
+	configDoc["Runners"], _ = json.Marshal([]map[string]json.RawMessage{entry})
+
+	if err := writeConfigDocument(); err != nil {
+		return err
+	}
+
+	printLog("Config " + configFile + " has been upgraded to the multi-runner format")
+	return nil
+}
+
+func splitConfig(c Config) (base map[string]json.RawMessage, entry map[string]json.RawMessage) {
+
+	if len(c.TokenCommand) > 0 {
+		c.Token = ""
+	}
+	c.Runners = nil
+
+	var data, _ = json.Marshal(&c)
+	json.Unmarshal(data, &base)
+	delete(base, "Runners")
+
+	entry = map[string]json.RawMessage{}
+	for _, key := range runnerKeys {
+		if val, ok := base[key]; ok {
+			entry[key] = val
+			delete(base, key)
+		}
+	}
+	return base, entry
+}
+
+func configRunners() []map[string]json.RawMessage {
+
+	var runners []map[string]json.RawMessage
+	json.Unmarshal(configDoc["Runners"], &runners)
+	return runners
+}
+
+func defineRunners() {
+
+	var base = map[string]json.RawMessage{}
+	for key, val := range configDoc {
+		if key != "Runners" {
+			base[key] = val
+		}
+	}
+	var baseData, _ = json.Marshal(base)
+
+	var runners = configRunners()
+	if len(runners) == 0 {
+		printErr("Config " + configFile + " has no runners, register one with: runner register")
+	}
+
+	runnerConfigs = nil
+	for i, entry := range runners {
+
+		config = Config{}
+		var data, _ = json.Marshal(entry)
+		if err := json.Unmarshal(baseData, &config); err != nil {
+			printErr(err.Error())
+		}
+		if err := json.Unmarshal(data, &config); err != nil {
+			printErr("Invalid runner " + strconv.Itoa(i+1) + " in " + configFile + ": " + err.Error())
+		}
+		config.Runners = nil
+		applyEnvConfig()
+		if config.WorkDir == "" {
+			config.WorkDir = defaultWorkDir()
+		}
+
+		loadToken()
+		checkConfig()
+		runnerConfigs = append(runnerConfigs, config)
+	}
+}
+
+func useRunner(index int) {
+
+	if runnerIndex >= 0 && runnerIndex < len(runnerConfigs) {
+		runnerConfigs[runnerIndex] = config
+	}
+
+	config = runnerConfigs[index]
+	runnerIndex = index
+
+	checkConfig()
+	defineClient()
+
+	if len(runnerConfigs) > 1 {
+		printDebug("Polling runner " + strconv.Itoa(index+1) + " of " + strconv.Itoa(len(runnerConfigs)) + " at " + config.URL)
+	}
+}
+
+func prepareWorkDir() {
+
+	if err := os.MkdirAll(config.WorkDir, 0755); err != nil {
+		if os.IsPermission(err) {
+			checkVolume(filepath.Dir(config.WorkDir))
+		}
+		printErr(err.Error())
+	}
+	checkVolume(config.WorkDir)
+
+	cleanSpool()
+	cleanProjects()
+	cleanTraceLogs()
+}
+
+func checkConfig() {
+
+	checkTraceProcessors()
+	checkStderr()
+	checkExecutors()
+	checkCapabilities()
+	checkImages()
+	checkStepTimeouts()
+	checkKillTimeout()
+	checkRetry()
+	checkMaintenance()
+	checkUsers()
+	checkLocale()
+	checkEgress()
+	checkSecrets()
+	checkWebhooks()
+}
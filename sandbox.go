@@ -0,0 +1,111 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+const sandboxScript = `set -e
+mount --make-rprivate /
+while [ "$1" != -- ]; do mount --bind "$1" "$1"; shift; done
+shift
+mount -o remount,bind,ro /
+cd "$PWD"
+if command -v ip >/dev/null; then ip link set lo up 2>/dev/null || true; fi
+exec "$@"`
+
+type SandboxConfig struct {
+	Network bool
+	Paths   []string
+	Seccomp string
+}
+
+type sandboxExecutor struct {
+	shellExecutor
+}
+
+func newSandboxExecutor(jc *JobContext) Executor {
+	return sandboxExecutor{}
+}
+
+func (e sandboxExecutor) Prepare(jc *JobContext, src Source) error {
+
+	if err := checkSandbox(); err != nil {
+		return UnsupportedError("The sandbox executor " + err.Error())
+	}
+	if jc.user == nil {
+		return UnsupportedError("The sandbox executor requires a job user, configure User for this job")
+	}
+
+	return e.shellExecutor.Prepare(jc, src)
+}
+
+func (sandboxExecutor) Run(jc *JobContext, name string, args []string, stdin []string) error {
+
+	if runtime.GOOS != "linux" {
+		return UnsupportedError("The sandbox executor requires Linux namespaces")
+	}
+
+	var unshare = []string{"--mount", "--pid", "--fork", "--mount-proc", "--ipc", "--uts"}
+	if !config.Sandbox.Network || jc.egress.Mode == "off" {
+		unshare = append(unshare, "--net")
+	}
+	unshare = append(unshare, "--", "sh", "-c", sandboxScript, "sandbox", jc.projDir, jc.cacheDir, jc.tmpDir)
+	unshare = append(append(unshare, config.Sandbox.Paths...), "--")
+
+	if jc.user != nil {
+		var groups []string
+		for _, val := range jc.user.credential.Groups {
+			groups = append(groups, strconv.FormatUint(uint64(val), 10))
+		}
+		unshare = append(unshare, "setpriv",
+			"--reuid="+strconv.FormatUint(uint64(jc.user.credential.Uid), 10),
+			"--regid="+strconv.FormatUint(uint64(jc.user.credential.Gid), 10))
+		if groups != nil {
+			unshare = append(unshare, "--groups="+strings.Join(groups, ","))
+		} else {
+			unshare = append(unshare, "--clear-groups")
+		}
+		if config.Sandbox.Seccomp != "" {
+			unshare = append(unshare, "--no-new-privs", "--seccomp-filter="+config.Sandbox.Seccomp)
+		}
+		unshare = append(unshare, "--")
+	}
+
+	var cmd = exec.Command("unshare", append(append(unshare, name), args...)...)
+	cmd.Dir = jc.scriptDir
+	cmd.Env = jc.environ()
+
+	return jc.runCmd(cmd, stdin)
+}
+
+func checkSandbox() error {
+
+	if runtime.GOOS != "linux" {
+		return errors.New("requires Linux namespaces")
+	}
+	if os.Geteuid() != 0 {
+		return errors.New("requires the runner to run as root")
+	}
+
+	for _, name := range []string{"unshare", "setpriv"} {
+		if _, err := exec.LookPath(name); err != nil {
+			return errors.New("requires " + name + ", which is not available")
+		}
+	}
+
+	if config.Sandbox.Seccomp != "" {
+		if _, err := os.Stat(config.Sandbox.Seccomp); err != nil {
+			return errors.New("requires the seccomp filter " + config.Sandbox.Seccomp + ", which is not available")
+		}
+		if help, _ := exec.Command("setpriv", "--help").Output(); !bytes.Contains(help, []byte("--seccomp-filter")) {
+			return errors.New("requires setpriv with --seccomp-filter support (util-linux 2.40 or later) for the seccomp filter")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+type Secret struct {
+	Provider string
+	Path     string
+	Field    string
+	Command  []string
+}
+
+type VaultConfig struct {
+	Addr      string
+	Token     string
+	TokenFile string
+}
+
+type SecretError string
+
+var secretProviders = map[string]func(spec Secret) (string, error){
+	"file":  fileSecret,
+	"exec":  execSecret,
+	"vault": vaultSecret,
+}
+
+func (jc *JobContext) loadSecrets(configJob *ConfigJob) error {
+
+	var secrets = map[string]Secret{}
+	for key, val := range config.Secrets {
+		secrets[key] = val
+	}
+	if configJob != nil {
+		for key, val := range configJob.Secrets {
+			secrets[key] = val
+		}
+	}
+
+	for key, spec := range secrets {
+
+		var value, err = secretProviders[spec.Provider](spec)
+		if err != nil {
+			return SecretError("Fetching secret " + key + " from " + spec.Provider + " failed: " + err.Error())
+		}
+
+		jc.setEnv(key, value)
+		jc.job.Variables = append(jc.job.Variables, Variable{Key: key, Value: value, Masked: true})
+	}
+
+	return nil
+}
+
+func checkSecrets() {
+
+	var check = func(secrets map[string]Secret) {
+		for key, val := range secrets {
+			if _, ok := secretProviders[val.Provider]; !ok {
+				printErr("Unknown secret provider for " + key + ": " + val.Provider)
+			}
+		}
+	}
+
+	check(config.Secrets)
+	for _, val := range config.Jobs {
+		check(val.Secrets)
+	}
+}
+
+func fileSecret(spec Secret) (string, error) {
+
+	var data, err = os.ReadFile(spec.Path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(data), "\r\n"), nil
+}
+
+func execSecret(spec Secret) (string, error) {
+
+	if len(spec.Command) == 0 {
+		return "", SecretError("no command configured")
+	}
+
+	var cmd = exec.Command(spec.Command[0], spec.Command[1:]...)
+	cmd.Stderr = os.Stderr
+
+	var data, err = cmd.Output()
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(data), "\r\n"), nil
+}
+
+func vaultSecret(spec Secret) (string, error) {
+
+	var addr = config.Vault.Addr
+	if addr == "" {
+		addr = os.Getenv("VAULT_ADDR")
+	}
+
+	var token = config.Vault.Token
+	if config.Vault.TokenFile != "" {
+		var data, err = os.ReadFile(config.Vault.TokenFile)
+		if err != nil {
+			return "", err
+		}
+		token = strings.TrimSpace(string(data))
+	}
+	if token == "" {
+		token = os.Getenv("VAULT_TOKEN")
+	}
+
+	var req, err = http.NewRequest("GET", strings.TrimSuffix(addr, "/")+"/v1/"+strings.TrimPrefix(spec.Path, "/"), nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("X-Vault-Token", token)
+
+	var client = &http.Client{Timeout: time.Second * config.ConnectionTimeout}
+	var resp *http.Response
+	if resp, err = client.Do(req); err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", SecretError(resp.Status)
+	}
+
+	var body struct {
+		Data map[string]any
+	}
+	if err = json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", err
+	}
+
+	var data = body.Data
+	if inner, ok := data["data"].(map[string]any); ok {
+		data = inner
+	}
+
+	var field = spec.Field
+	if field == "" {
+		field = "value"
+	}
+
+	var value, ok = data[field].(string)
+	if !ok {
+		return "", SecretError("field " + field + " not found in " + spec.Path)
+	}
+	return value, nil
+}
+
+func (err SecretError) Error() string {
+	return string(err)
+}
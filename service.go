@@ -0,0 +1,206 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+const defaultServiceName = "neo-runner"
+
+const systemdUnit = `[Unit]
+Description=neo-mode GitLab runner
+After=network-online.target
+Wants=network-online.target
+
+[Service]
+Type=notify
+ExecStart={Exec} --config {Config}
+ExecReload=/bin/kill -USR2 $MAINPID
+{User}Restart=always
+RestartSec=5
+WatchdogSec=10min
+KillMode=mixed
+Delegate=yes
+TimeoutStopSec=1h
+StandardOutput=journal
+StandardError=journal
+
+[Install]
+WantedBy={Target}
+`
+
+const launchdPlist = `<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+<dict>
+	<key>Label</key>
+	<string>{Name}</string>
+	<key>ProgramArguments</key>
+	<array>
+		<string>{Exec}</string>
+		<string>--config</string>
+		<string>{Config}</string>
+	</array>
+{User}	<key>RunAtLoad</key>
+	<true/>
+	<key>KeepAlive</key>
+	<true/>
+	<key>ThrottleInterval</key>
+	<integer>5</integer>
+	<key>StandardOutPath</key>
+	<string>{Log}</string>
+	<key>StandardErrorPath</key>
+	<string>{Log}</string>
+</dict>
+</plist>
+`
+
+type serviceManager struct {
+	file     string
+	template string
+	user     string
+	log      string
+	target   string
+	install  [][]string
+	remove   [][]string
+	cleanup  [][]string
+	start    []string
+	stop     []string
+}
+
+func serviceCommand(command string, args []string) {
+
+	var flags = flag.NewFlagSet(command, flag.ExitOnError)
+	var name = flags.String("name", defaultServiceName, "Service name")
+	var user = flags.String("user", "", "User account to run the service as (system services only)")
+	flags.Parse(args)
+
+	var manager = defineServiceManager(*name, *user)
+
+	switch command {
+	case "install":
+		installService(*name, manager)
+	case "uninstall":
+		runCommands(manager.remove...)
+		if err := os.Remove(manager.file); err != nil && !os.IsNotExist(err) {
+			printErr(err.Error())
+		}
+		runCommands(manager.cleanup...)
+		printLog("Removed service " + *name)
+	case "start":
+		runCommands(manager.start)
+	case "stop":
+		runCommands(manager.stop)
+	}
+
+	os.Exit(0)
+}
+
+func defineServiceManager(name, user string) serviceManager {
+
+	var isSystem = os.Geteuid() == 0
+	var home, _ = os.UserHomeDir()
+
+	switch runtime.GOOS {
+	case "linux":
+		var manager = serviceManager{template: systemdUnit, target: "multi-user.target"}
+		var systemctl = []string{"systemctl"}
+
+		if isSystem {
+			manager.file = "/etc/systemd/system/" + name + ".service"
+			if user != "" {
+				manager.user = "User=" + user + "\n"
+			}
+		} else {
+			manager.file = filepath.Join(home, ".config/systemd/user", name+".service")
+			manager.target = "default.target"
+			systemctl = append(systemctl, "--user")
+		}
+
+		manager.install = [][]string{append(systemctl, "daemon-reload"), append(systemctl, "enable", name)}
+		manager.remove = [][]string{append(systemctl, "disable", "--now", name)}
+		manager.cleanup = [][]string{append(systemctl, "daemon-reload")}
+		manager.start = append(systemctl, "start", name)
+		manager.stop = append(systemctl, "stop", name)
+		return manager
+
+	case "darwin":
+		var manager = serviceManager{template: launchdPlist}
+
+		if isSystem {
+			manager.file = "/Library/LaunchDaemons/" + name + ".plist"
+			manager.log = "/var/log/" + name + ".log"
+			if user != "" {
+				manager.user = "\t<key>UserName</key>\n\t<string>" + user + "</string>\n"
+			}
+		} else {
+			manager.file = filepath.Join(home, "Library/LaunchAgents", name+".plist")
+			manager.log = filepath.Join(home, "Library/Logs", name+".log")
+		}
+
+		manager.install = [][]string{{"launchctl", "load", "-w", manager.file}}
+		manager.remove = [][]string{{"launchctl", "unload", "-w", manager.file}}
+		manager.start = []string{"launchctl", "start", name}
+		manager.stop = []string{"launchctl", "stop", name}
+		return manager
+	}
+
+	printErr("Service management is not supported on " + runtime.GOOS)
+	return serviceManager{}
+}
+
+func installService(name string, manager serviceManager) {
+
+	var executable, err = os.Executable()
+	if err == nil {
+		executable, err = filepath.EvalSymlinks(executable)
+	}
+	if err != nil {
+		printErr("Resolving runner executable failed: " + err.Error())
+	}
+
+	var configPath string
+	if configPath, err = filepath.Abs(configFile); err != nil {
+		printErr(err.Error())
+	}
+	if _, err = os.Stat(configPath); err != nil {
+		printErr("Config file " + configPath + " does not exist, register the runner first")
+	}
+
+	var content = strings.NewReplacer(
+		"{Name}", name,
+		"{Exec}", executable,
+		"{Config}", configPath,
+		"{User}", manager.user,
+		"{Log}", manager.log,
+		"{Target}", manager.target,
+	).Replace(manager.template)
+
+	if err = os.MkdirAll(filepath.Dir(manager.file), 0755); err == nil {
+		err = os.WriteFile(manager.file, []byte(content), 0644)
+	}
+	if err != nil {
+		printErr("Writing " + manager.file + " failed: " + err.Error())
+	}
+
+	runCommands(manager.install...)
+	printLog("Installed service " + name + " in " + manager.file)
+}
+
+func runCommands(commands ...[]string) {
+
+	for _, val := range commands {
+
+		var cmd = exec.Command(val[0], val[1:]...)
+		cmd.Stdout = os.Stdout
+		cmd.Stderr = os.Stderr
+
+		if err := cmd.Run(); err != nil {
+			printErr(strings.Join(val, " ") + " failed: " + err.Error())
+		}
+	}
+}
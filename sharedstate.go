@@ -0,0 +1,77 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+func (jc *JobContext) sharedKey(merge *MergeState, jobName, digest string) string {
+
+	if config.StateStorage.Endpoint == "" || merge.Base == "" || merge.Source == "" {
+		return ""
+	}
+	return "merges/" + jc.projID + "/" + merge.Base + "-" + merge.Source + "/" + url.PathEscape(jobName) + "/" + digest
+}
+
+func (jc *JobContext) sharedSuccess(merge *MergeState, jobName, digest string) (string, bool) {
+
+	var key = jc.sharedKey(merge, jobName, digest)
+	if key == "" {
+		return "", false
+	}
+
+	var storage = config.StateStorage
+	var req, err = http.NewRequestWithContext(jc.ctx, http.MethodGet, storage.objectURL(key), nil)
+	if err != nil {
+		return "", false
+	}
+	storage.sign(req, emptyPayloadHash)
+
+	var res *http.Response
+	if res, err = storageClient.Do(req); err != nil {
+		jc.printTrace("Checking shared merge state failed: " + err.Error())
+		return "", false
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return "", false
+	}
+
+	var data, _ = io.ReadAll(io.LimitReader(res.Body, 4096))
+	return strings.TrimSpace(string(data)), true
+}
+
+func (jc *JobContext) markSharedSuccess(merge *MergeState, jobName, digest string) {
+
+	var key = jc.sharedKey(merge, jobName, digest)
+	if key == "" {
+		return
+	}
+
+	var body = merge.Pipelines[jobName]
+	var hash = sha256.Sum256([]byte(body))
+
+	var storage = config.StateStorage
+	var req, err = http.NewRequest(http.MethodPut, storage.objectURL(key), strings.NewReader(body))
+	if err != nil {
+		return
+	}
+	storage.sign(req, hex.EncodeToString(hash[:]))
+
+	var res *http.Response
+	if res, err = storageClient.Do(req); err == nil {
+		res.Body.Close()
+		if res.StatusCode != http.StatusOK {
+			err = errors.New(res.Status)
+		}
+	}
+	if err != nil {
+		jc.printTrace("Saving shared merge state failed: " + err.Error())
+	}
+}
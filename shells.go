@@ -0,0 +1,79 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+type shellScript func(script []string, isDebug bool) (args []string, stdin []string)
+
+var shells = map[string]shellScript{
+	"sh":         posixScript,
+	"bash":       posixScript,
+	"dash":       posixScript,
+	"ksh":        posixScript,
+	"zsh":        posixScript,
+	"fish":       fishScript,
+	"pwsh":       pwshScript,
+	"powershell": pwshScript,
+}
+
+func (jc *JobContext) defineShell(configJob *ConfigJob) {
+
+	jc.shell = config.Shell
+	if configJob != nil && configJob.Shell != "" {
+		jc.shell = configJob.Shell
+	}
+
+	if name := jc.variable("RUNNER_SHELL"); name != "" {
+		if _, ok := shells[name]; ok {
+			jc.shell = name
+		} else {
+			jc.printTrace("Ignoring unsupported RUNNER_SHELL " + name)
+		}
+	}
+}
+
+func (jc *JobContext) shellCommand(script []string) (string, []string, []string) {
+
+	var generate, ok = shells[strings.TrimSuffix(filepath.Base(jc.shell), ".exe")]
+	if !ok {
+		generate = posixScript
+	}
+
+	var args, stdin = generate(script, jc.isDebugTrace)
+	return jc.shell, args, stdin
+}
+
+func posixScript(script []string, isDebug bool) ([]string, []string) {
+
+	var lines = []string{"set -e"}
+	if isDebug {
+		lines = append(lines, "set -x")
+	}
+	return nil, append(lines, script...)
+}
+
+func fishScript(script []string, isDebug bool) ([]string, []string) {
+
+	var lines []string
+	if isDebug {
+		lines = append(lines, "set fish_trace 1")
+	}
+	for _, val := range script {
+		lines = append(lines, val, "or exit $status")
+	}
+	return nil, lines
+}
+
+func pwshScript(script []string, isDebug bool) ([]string, []string) {
+
+	var lines = []string{"$ErrorActionPreference = 'Stop'"}
+	if isDebug {
+		lines = append(lines, "Set-PSDebug -Trace 1")
+	}
+	for _, val := range script {
+		lines = append(lines, val, "if (!$?) { exit $(if ($LASTEXITCODE) { $LASTEXITCODE } else { 1 }) }")
+	}
+	return []string{"-NoProfile", "-NonInteractive", "-Command", "-"}, lines
+}
@@ -0,0 +1,8 @@
+//go:build !unix
+
+package main
+
+import "os"
+
+var pauseSignal os.Signal
+var upgradeSignal os.Signal
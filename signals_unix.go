@@ -0,0 +1,11 @@
+//go:build unix
+
+package main
+
+import (
+	"os"
+	"syscall"
+)
+
+var pauseSignal os.Signal = syscall.SIGUSR1
+var upgradeSignal os.Signal = syscall.SIGUSR2
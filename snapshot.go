@@ -0,0 +1,166 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"io/fs"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const defaultSnapshotLines = 100
+
+type stepTail struct {
+	name  string
+	lines []string
+}
+
+type snapshotWriter struct {
+	mu    sync.Mutex
+	max   int
+	line  []byte
+	steps []*stepTail
+}
+
+func (w *snapshotWriter) Write(p []byte) (int, error) {
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if len(w.steps) == 0 {
+		w.steps = append(w.steps, &stepTail{name: "prepare"})
+	}
+	var step = w.steps[len(w.steps)-1]
+
+	for _, c := range p {
+		if c != '\n' {
+			w.line = append(w.line, c)
+			continue
+		}
+
+		step.lines = append(step.lines, string(w.line))
+		if len(step.lines) > w.max {
+			step.lines = step.lines[1:]
+		}
+		w.line = w.line[:0]
+	}
+
+	return len(p), nil
+}
+
+func (jc *JobContext) createSnapshot() {
+
+	if !config.DebugSnapshot {
+		return
+	}
+
+	var max = config.DebugSnapshotLines
+	if max <= 0 {
+		max = defaultSnapshotLines
+	}
+	jc.snapshot = &snapshotWriter{max: max}
+}
+
+func (jc *JobContext) beginSnapshotStep(step string) {
+
+	if jc.snapshot == nil {
+		return
+	}
+
+	jc.snapshot.mu.Lock()
+	jc.snapshot.steps = append(jc.snapshot.steps, &stepTail{name: step})
+	jc.snapshot.mu.Unlock()
+}
+
+func (jc *JobContext) uploadSnapshot() {
+
+	if jc.snapshot == nil {
+		return
+	}
+
+	var data, err = jc.buildSnapshot()
+	if err == nil {
+		err = jc.postArtifact("archive", "zip", config.DebugSnapshotExpireIn, "debug.zip", data)
+	}
+	if err != nil {
+		jc.printTrace("Uploading debug snapshot failed: " + err.Error())
+		return
+	}
+
+	jc.printTrace("Uploaded debug snapshot")
+	jc.closeStderrLog()
+}
+
+func (jc *JobContext) buildSnapshot() ([]byte, error) {
+
+	var archive bytes.Buffer
+	var zw = zip.NewWriter(&archive)
+
+	var add = func(name, content string) error {
+		var f, err = zw.Create("debug/" + name)
+		if err != nil {
+			return err
+		}
+
+		var w = newMaskWriter(jc, nopCloser{f})
+		if _, err = w.Write([]byte(content)); err == nil {
+			err = w.Close()
+		}
+		return err
+	}
+
+	if err := add("environment.txt", strings.Join(jc.environ(), "\n")+"\n"); err != nil {
+		return nil, err
+	}
+
+	if err := add("workdir.txt", listDir(jc.scriptDir)); err != nil {
+		return nil, err
+	}
+
+	jc.snapshot.mu.Lock()
+	var steps = jc.snapshot.steps
+	jc.snapshot.mu.Unlock()
+
+	for i, step := range steps {
+		var name = "steps/" + strconv.Itoa(i) + "-" + strings.ReplaceAll(step.name, "/", "_") + ".log"
+		if err := add(name, strings.Join(step.lines, "\n")+"\n"); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := jc.addStderrLog(zw); err != nil {
+		return nil, err
+	}
+
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return archive.Bytes(), nil
+}
+
+func listDir(dir string) string {
+
+	var list strings.Builder
+	list.WriteString(dir + "\n")
+
+	filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+
+		if err != nil || path == dir {
+			return nil
+		}
+		if entry.IsDir() && entry.Name() == ".git" {
+			return filepath.SkipDir
+		}
+
+		var rel, _ = filepath.Rel(dir, path)
+		var info, _ = entry.Info()
+		if info != nil {
+			list.WriteString(info.Mode().String() + " " + strconv.FormatInt(info.Size(), 10) + " " + rel + "\n")
+		}
+		return nil
+	})
+
+	return list.String()
+}
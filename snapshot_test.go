@@ -0,0 +1,60 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestBuildSnapshot(t *testing.T) {
+
+	t.Setenv("RUNNER_ONLY_VALUE", "process")
+	config = Config{}
+
+	var job = &Job{Variables: []Variable{{Key: "TOKEN", Value: "s3cr3t", Masked: true}}}
+	var jc = newJobContext(job, nil)
+	jc.setEnv("TOKEN", "s3cr3t")
+	jc.scriptDir = t.TempDir()
+	jc.snapshot = &snapshotWriter{max: 10}
+	jc.snapshot.Write([]byte("using s3cr3t\n"))
+
+	var err error
+	if jc.stderrLog, err = os.CreateTemp(t.TempDir(), "stderr-*"); err != nil {
+		t.Fatal(err)
+	}
+	defer jc.closeStderrLog()
+	jc.stderrLog.WriteString("warning: something\n")
+
+	var data []byte
+	data, err = jc.buildSnapshot()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var zr *zip.Reader
+	if zr, err = zip.NewReader(bytes.NewReader(data), int64(len(data))); err != nil {
+		t.Fatal(err)
+	}
+
+	var files = map[string]string{}
+	for _, f := range zr.File {
+		var r, _ = f.Open()
+		var content, _ = io.ReadAll(r)
+		r.Close()
+		files[f.Name] = string(content)
+	}
+
+	var env = files["debug/environment.txt"]
+	if !strings.Contains(env, "TOKEN=[MASKED]\n") || strings.Contains(env, "RUNNER_ONLY_VALUE") {
+		t.Errorf("environment.txt = %q", env)
+	}
+	if log := files["debug/steps/0-prepare.log"]; log != "using [MASKED]\n" {
+		t.Errorf("step log = %q", log)
+	}
+	if log := files["stderr.log"]; log != "warning: something\n" {
+		t.Errorf("stderr.log = %q", log)
+	}
+}
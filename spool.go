@@ -0,0 +1,172 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/neo-mode/runner-api"
+)
+
+const traceRetries = 5
+
+const traceInterval = 3 * time.Second
+
+func spoolDir() string {
+	return config.WorkDir + "/.spool"
+}
+
+func cleanSpool() {
+
+	var names, _ = filepath.Glob(spoolDir() + "/trace-*")
+	for _, val := range names {
+		os.Remove(val)
+	}
+}
+
+func (jc *JobContext) createSpool() error {
+
+	if err := os.MkdirAll(spoolDir(), 0700); err != nil {
+		return err
+	}
+
+	var err error
+	jc.spool, err = os.CreateTemp(spoolDir(), "trace-*")
+	return err
+}
+
+func (jc *JobContext) startTraceUpload() (stop func()) {
+
+	var done = make(chan struct{})
+	var stopped = make(chan struct{})
+	go func() {
+		defer close(stopped)
+		var ticker = time.NewTicker(traceInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				jc.flushTrace()
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	return func() {
+		close(done)
+		<-stopped
+	}
+}
+
+func (jc *JobContext) flushTrace() {
+
+	if jc.spool == nil {
+		jc.traceMu.Lock()
+		var data = append([]byte(nil), jc.trace.Bytes()...)
+		jc.traceMu.Unlock()
+
+		jc.uploadTrace(data)
+		return
+	}
+
+	if info, err := jc.spool.Stat(); err == nil && info.Size() > int64(jc.traceSize) {
+		if data, release, err := mapFile(jc.spool, int(info.Size())); err == nil {
+			jc.uploadTrace(data)
+			release()
+		}
+	}
+}
+
+func (jc *JobContext) sendTrace() {
+
+	jc.flushTrace()
+	if jc.spool == nil {
+		jc.trace.Reset()
+		return
+	}
+
+	jc.spool.Close()
+	os.Remove(jc.spool.Name())
+	jc.spool = nil
+}
+
+func (jc *JobContext) uploadTrace(data []byte) {
+
+	var size = config.TraceChunkSize << 10
+	if size <= 0 {
+		size = len(data)
+	}
+
+	var offset, attempts = jc.traceSize, 0
+	for offset < len(data) {
+
+		var end = offset + size
+		if end > len(data) {
+			end = len(data)
+		}
+
+		var next, err = jc.sendTraceChunk(data[offset:end], offset)
+		if err == nil {
+			offset, attempts = next, 0
+			jc.traceSize = offset
+			continue
+		}
+
+		if _, ok := err.(runner.APIError); ok || attempts == traceRetries {
+			printLog("Uploading trace of job " + jc.jobID + " failed: " + err.Error())
+			return
+		}
+
+		attempts++
+		time.Sleep(time.Duration(attempts) * time.Second)
+		waitRateLimit()
+	}
+}
+
+func (jc *JobContext) sendTraceChunk(chunk []byte, offset int) (int, error) {
+
+	var req, err = http.NewRequest(http.MethodPatch, apiURL("/jobs/"+jc.jobID+"/trace"), bytes.NewReader(chunk))
+	if err != nil {
+		return offset, err
+	}
+
+	req.Header.Set("Content-Type", "text/plain")
+	req.Header.Set("JOB-TOKEN", jc.job.Token)
+	req.Header.Set("Content-Range", strconv.Itoa(offset)+"-"+strconv.Itoa(offset+len(chunk)-1))
+
+	var res *http.Response
+	if res, err = runner.Client.Do(req); err != nil {
+		return offset, err
+	}
+
+	io.Copy(io.Discard, res.Body)
+	res.Body.Close()
+
+	switch res.StatusCode {
+	case http.StatusAccepted, http.StatusOK:
+		return offset + len(chunk), nil
+
+	case http.StatusRequestedRangeNotSatisfiable:
+		var rangeEnd = res.Header.Get("Range")
+		if i := strings.IndexByte(rangeEnd, '-'); i >= 0 {
+			rangeEnd = rangeEnd[i+1:]
+		}
+		if next, err := strconv.Atoi(rangeEnd); err == nil && next <= offset+len(chunk) {
+			return next, nil
+		}
+		return offset, runner.APIError(res.Status)
+
+	case http.StatusForbidden, http.StatusNotFound:
+		return offset, runner.APIError(res.Status)
+	}
+
+	return offset, errors.New(res.Status)
+}
@@ -0,0 +1,223 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const defaultMergeRetentionDays = 14
+
+var digestExcludedPrefixes = []string{"CI_", "GITLAB_", "RUNNER_"}
+
+type ProjectState struct {
+	PipelineID string
+	Sha        string
+	Target     string
+	Merges     map[string]*MergeState
+}
+
+type MergeState struct {
+	TargetName string
+	Base       string
+	Source     string
+	Result     string
+	Succeeded  map[string]string
+	Pipelines  map[string]string
+	Digests    map[string]string
+	UsedAt     time.Time
+}
+
+func stateFile(projDir string) string {
+
+	var name = strings.ReplaceAll(strings.Trim(filepath.ToSlash(projDir), "/"), "/", "_")
+	return config.WorkDir + "/.state/" + name + ".json"
+}
+
+func (jc *JobContext) loadState() {
+
+	jc.state = ProjectState{}
+
+	var data, err = os.ReadFile(stateFile(jc.projDir))
+	if err != nil {
+		return
+	}
+
+	if json.Unmarshal(data, &jc.state) != nil {
+		jc.state = ProjectState{}
+		return
+	}
+
+	if jc.state.Target != "" && !jc.hasRef(jc.state.Target) {
+		jc.state.PipelineID = ""
+	}
+
+	var retention = config.MergeRetentionDays
+	if retention == 0 {
+		retention = defaultMergeRetentionDays
+	}
+	var deadline = time.Now().AddDate(0, 0, -retention)
+
+	for key, val := range jc.state.Merges {
+
+		if retention > 0 && val.UsedAt.Before(deadline) || val.Result == "" || !jc.hasRef(val.Result) {
+			delete(jc.state.Merges, key)
+			continue
+		}
+
+		for name, sha := range val.Succeeded {
+			if sha != val.Result {
+				delete(val.Succeeded, name)
+				delete(val.Pipelines, name)
+				delete(val.Digests, name)
+			}
+		}
+	}
+}
+
+func (jc *JobContext) saveState() {
+
+	var data, err = json.Marshal(jc.state)
+	if err != nil {
+		return
+	}
+
+	var name = stateFile(jc.projDir)
+	if err = writeStateFile(name, data); err != nil {
+		printLog("Saving project state to " + name + " failed: " + err.Error())
+	}
+}
+
+func writeStateFile(name string, data []byte) error {
+
+	if err := os.MkdirAll(filepath.Dir(name), 0700); err != nil {
+		return err
+	}
+
+	os.Remove(name + ".tmp")
+	var f, err = os.OpenFile(name+".tmp", os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+
+	_, err = f.Write(data)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(name+".tmp", name)
+	}
+	return err
+}
+
+func (jc *JobContext) dropMerges(targetName string) {
+
+	for key, val := range jc.state.Merges {
+		if val.TargetName == targetName {
+			delete(jc.state.Merges, key)
+		}
+	}
+}
+
+func (jc *JobContext) saveMerge(targetName, sourceName, mergeID string) {
+
+	var result, err = jc.gitOutput("rev-parse", "HEAD")
+	if err != nil {
+		return
+	}
+	var base, _ = jc.gitOutput("rev-parse", "origin/"+targetName)
+	var source, _ = jc.gitOutput("rev-parse", "origin/"+sourceName)
+
+	if jc.state.Merges == nil {
+		jc.state.Merges = map[string]*MergeState{}
+	}
+
+	if merge, ok := jc.state.Merges[mergeID]; ok && merge.Result == strings.TrimSpace(result) {
+		merge.UsedAt = time.Now()
+		jc.saveState()
+		return
+	}
+
+	jc.state.Merges[mergeID] = &MergeState{
+		TargetName: targetName,
+		Base:       strings.TrimSpace(base),
+		Source:     strings.TrimSpace(source),
+		Result:     strings.TrimSpace(result),
+		Succeeded:  map[string]string{},
+		UsedAt:     time.Now(),
+	}
+	jc.saveState()
+}
+
+func (jc *JobContext) jobDigest(configJob *ConfigJob) string {
+
+	var definition = struct {
+		Steps     []Step
+		Command   *ConfigJob
+		Variables map[string]string
+		Config    map[string]string
+	}{jc.job.Steps, configJob, map[string]string{}, config.Variables}
+
+	for _, val := range jc.job.Variables {
+		if !hasAnyPrefix(val.Key, digestExcludedPrefixes) {
+			definition.Variables[val.Key] = val.Value
+		}
+	}
+
+	var data, _ = json.Marshal(definition)
+	var hash = sha256.Sum256(data)
+	return hex.EncodeToString(hash[:])
+}
+
+func hasAnyPrefix(s string, prefixes []string) bool {
+
+	for _, val := range prefixes {
+		if strings.HasPrefix(s, val) {
+			return true
+		}
+	}
+	return false
+}
+
+func (jc *JobContext) cachedSuccess(mergeID, jobName, digest string) (string, bool) {
+
+	var merge, ok = jc.state.Merges[mergeID]
+	if !ok {
+		return "", false
+	}
+	if merge.Result == jc.state.Target && merge.Succeeded[jobName] == merge.Result {
+		if merge.Digests[jobName] == digest {
+			return merge.Pipelines[jobName], true
+		}
+		jc.printTrace("Job definition changed since it passed on this merged result, running it again")
+		return "", false
+	}
+	return jc.sharedSuccess(merge, jobName, digest)
+}
+
+func (jc *JobContext) markSucceeded(mergeID, jobName, digest string) {
+
+	var merge, ok = jc.state.Merges[mergeID]
+	if !ok {
+		return
+	}
+
+	if merge.Succeeded == nil {
+		merge.Succeeded = map[string]string{}
+	}
+	if merge.Pipelines == nil {
+		merge.Pipelines = map[string]string{}
+	}
+	if merge.Digests == nil {
+		merge.Digests = map[string]string{}
+	}
+	merge.Succeeded[jobName] = merge.Result
+	merge.Pipelines[jobName] = jc.variable("CI_PIPELINE_URL")
+	merge.Digests[jobName] = digest
+	jc.saveState()
+	jc.markSharedSuccess(merge, jobName, digest)
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"io"
+	"os/exec"
+	"testing"
+)
+
+func TestSaveMergeKeepsSucceededJobs(t *testing.T) {
+
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not available")
+	}
+
+	config = Config{WorkDir: t.TempDir()}
+	var projDir = t.TempDir()
+	var git = func(args ...string) {
+		var cmd = exec.Command("git", args...)
+		cmd.Dir = projDir
+		cmd.Env = []string{"GIT_AUTHOR_NAME=ci", "GIT_AUTHOR_EMAIL=ci@example.com", "GIT_COMMITTER_NAME=ci", "GIT_COMMITTER_EMAIL=ci@example.com", "HOME=" + projDir}
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+	git("init", "-q")
+	git("commit", "-q", "--allow-empty", "-m", "target")
+	git("update-ref", "refs/remotes/origin/master", "HEAD")
+	git("update-ref", "refs/remotes/origin/feature", "HEAD")
+
+	var runJob = func(name string) {
+		var jc = newJobContext(&Job{}, nil)
+		jc.traceWriter = nopCloser{io.Discard}
+		jc.projDir = projDir
+		jc.loadState()
+		jc.saveMerge("master", "feature", "42")
+		jc.markSucceeded("42", name, "digest-"+name)
+	}
+	runJob("build")
+	runJob("test")
+
+	var jc = newJobContext(&Job{}, nil)
+	jc.projDir = projDir
+	jc.loadState()
+
+	var merge = jc.state.Merges["42"]
+	if merge == nil {
+		t.Fatal("merge state was not saved")
+	}
+	for _, name := range []string{"build", "test"} {
+		if merge.Succeeded[name] != merge.Result || merge.Digests[name] != "digest-"+name {
+			t.Errorf("job %s is not marked as succeeded: %+v", name, merge)
+		}
+	}
+}
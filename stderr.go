@@ -0,0 +1,161 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"os"
+	"os/exec"
+	"sync"
+)
+
+var stderrModes = map[string]func(line []byte) []byte{
+	"prefix": prefixStderrLine,
+	"color":  colorStderrLine,
+}
+
+type syncWriter struct {
+	mu *sync.Mutex
+	w  io.Writer
+}
+
+func (w syncWriter) Write(p []byte) (int, error) {
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.w.Write(p)
+}
+
+func checkStderr() {
+
+	if config.Stderr != "" && stderrModes[config.Stderr] == nil {
+		printErr("Unknown stderr mode: " + config.Stderr)
+	}
+}
+
+func prefixStderrLine(line []byte) []byte {
+	return append([]byte("[stderr] "), line...)
+}
+
+func colorStderrLine(line []byte) []byte {
+
+	var text = bytes.TrimSuffix(line, []byte("\n"))
+	var colored = append(append([]byte("\x1b[31m"), text...), "\x1b[0m"...)
+	if len(text) < len(line) {
+		colored = append(colored, '\n')
+	}
+	return colored
+}
+
+func (jc *JobContext) splitOutput(cmd *exec.Cmd) (flush func()) {
+
+	if config.Stderr == "" && jc.stderrLog == nil {
+		cmd.Stdout = jc.traceWriter
+		cmd.Stderr = jc.traceWriter
+		return func() {}
+	}
+
+	var mu sync.Mutex
+	var trace = syncWriter{&mu, jc.traceWriter}
+
+	var stderr io.WriteCloser = nopCloser{trace}
+	if fn := stderrModes[config.Stderr]; fn != nil {
+		stderr = &lineWriter{next: stderr, fn: fn}
+	}
+
+	cmd.Stdout = trace
+	cmd.Stderr = stderr
+
+	var writers = []io.WriteCloser{stderr}
+	if jc.stderrLog != nil {
+		var log = &lineWriter{next: nopCloser{jc.stderrLog}, fn: jc.maskLine}
+		writers = append(writers, log)
+		cmd.Stderr = io.MultiWriter(stderr, log)
+	}
+
+	return func() {
+		for _, val := range writers {
+			val.Close()
+		}
+	}
+}
+
+func (jc *JobContext) createStderrLog() {
+
+	if !config.StderrArtifact {
+		return
+	}
+
+	var err = os.MkdirAll(spoolDir(), 0700)
+	if err == nil {
+		jc.stderrLog, err = os.CreateTemp(spoolDir(), "stderr-*")
+	}
+	if err != nil {
+		printLog("Creating stderr log for job " + jc.jobID + " failed: " + err.Error())
+	}
+}
+
+func (jc *JobContext) uploadStderrLog() {
+
+	if jc.stderrLog == nil {
+		return
+	}
+
+	defer jc.closeStderrLog()
+
+	if info, err := jc.stderrLog.Stat(); err != nil || info.Size() == 0 {
+		return
+	}
+
+	var data, err = jc.buildStderrArchive()
+	if err == nil {
+		err = jc.postArtifact("archive", "zip", "", "stderr.zip", data)
+	}
+	if err != nil {
+		jc.printTrace("Uploading stderr log failed: " + err.Error())
+		return
+	}
+
+	jc.printTrace("Uploaded stderr log")
+}
+
+func (jc *JobContext) closeStderrLog() {
+
+	if jc.stderrLog != nil {
+		jc.stderrLog.Close()
+		os.Remove(jc.stderrLog.Name())
+		jc.stderrLog = nil
+	}
+}
+
+func (jc *JobContext) buildStderrArchive() ([]byte, error) {
+
+	var archive bytes.Buffer
+	var zw = zip.NewWriter(&archive)
+
+	var err = jc.addStderrLog(zw)
+	if err == nil {
+		err = zw.Close()
+	}
+
+	return archive.Bytes(), err
+}
+
+func (jc *JobContext) addStderrLog(zw *zip.Writer) error {
+
+	if jc.stderrLog == nil {
+		return nil
+	}
+	if info, err := jc.stderrLog.Stat(); err != nil || info.Size() == 0 {
+		return err
+	}
+
+	var f, err = zw.Create("stderr.log")
+	if err == nil {
+		_, err = jc.stderrLog.Seek(0, io.SeekStart)
+	}
+	if err == nil {
+		_, err = io.Copy(f, jc.stderrLog)
+	}
+	return err
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"time"
+)
+
+const defaultKillTimeout = 5 * time.Second
+
+type StepTimeoutError string
+
+func (jc *JobContext) defineStepTimeouts(configJob *ConfigJob) {
+
+	jc.timeouts = map[string]time.Duration{}
+	for key, val := range config.StepTimeouts {
+		jc.timeouts[key], _ = time.ParseDuration(val)
+	}
+
+	if configJob == nil {
+		return
+	}
+
+	for key, val := range configJob.StepTimeouts {
+		jc.timeouts[key], _ = time.ParseDuration(val)
+	}
+}
+
+func checkStepTimeouts() {
+
+	var maps = []map[string]string{config.StepTimeouts}
+	for _, val := range config.Jobs {
+		maps = append(maps, val.StepTimeouts)
+	}
+
+	for _, timeouts := range maps {
+		for key, val := range timeouts {
+			if _, err := time.ParseDuration(val); err != nil {
+				printErr("Invalid timeout for step " + key + ": " + err.Error())
+			}
+		}
+	}
+}
+
+func checkKillTimeout() {
+
+	if config.KillTimeout == "" {
+		return
+	}
+	if _, err := time.ParseDuration(config.KillTimeout); err != nil {
+		printErr("Invalid kill timeout: " + err.Error())
+	}
+}
+
+func killTimeout() time.Duration {
+
+	var timeout, err = time.ParseDuration(config.KillTimeout)
+	if err != nil {
+		return defaultKillTimeout
+	}
+	return timeout
+}
+
+func (jc *JobContext) execStep(step string, name string, args []string, stdin []string) error {
+
+	jc.beginSnapshotStep(step)
+
+	var timeout = jc.timeouts[step]
+	if timeout <= 0 {
+		return jc.execScript(name, args, stdin)
+	}
+
+	var cancel context.CancelFunc
+	jc.stepCtx, cancel = context.WithTimeout(jc.ctx, timeout)
+
+	var err = jc.execScript(name, args, stdin)
+	if err != nil && jc.ctx.Err() == nil && jc.stepCtx.Err() == context.DeadlineExceeded {
+		err = StepTimeoutError("Step " + step + " exceeded its timeout of " + timeout.String())
+	}
+
+	cancel()
+	jc.stepCtx = jc.ctx
+
+	return err
+}
+
+func matchesWhen(when string, isSuccess bool) bool {
+
+	switch when {
+	case "always":
+		return true
+	case "on_failure":
+		return !isSuccess
+	}
+
+	return isSuccess
+}
+
+func (err StepTimeoutError) Error() string {
+	return string(err)
+}
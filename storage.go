@@ -0,0 +1,300 @@
+package main
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const emptyPayloadHash = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+
+type ObjectStorage struct {
+	Endpoint  string
+	Bucket    string
+	Region    string
+	Prefix    string
+	AccessKey string
+	SecretKey string
+}
+
+var storageClient = &http.Client{Timeout: 10 * time.Minute}
+
+func (s ObjectStorage) objectURL(key string) string {
+	return strings.TrimSuffix(s.Endpoint, "/") + "/" + s.Bucket + "/" + s.Prefix + key
+}
+
+func (jc *JobContext) restoreCache() {
+
+	var storage = config.CacheStorage
+	if storage.Endpoint == "" {
+		return
+	}
+
+	var req, err = http.NewRequestWithContext(jc.ctx, http.MethodGet, storage.objectURL(jc.projID+".tar.gz"), nil)
+	if err != nil {
+		jc.printTrace("Restoring cache from object storage failed, using the local cache: " + err.Error())
+		return
+	}
+	if info, err := os.Stat(jc.cacheDir); err == nil && !isEmptyDir(jc.cacheDir) {
+		req.Header.Set("If-Modified-Since", info.ModTime().UTC().Format(http.TimeFormat))
+	}
+	storage.sign(req, emptyPayloadHash)
+
+	var res *http.Response
+	if res, err = storageClient.Do(req); err != nil {
+		jc.printTrace("Restoring cache from object storage failed, using the local cache: " + err.Error())
+		return
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode == http.StatusNotModified {
+		return
+	}
+	if res.StatusCode == http.StatusNotFound {
+		jc.printTrace("No shared cache found in object storage, using the local cache")
+		return
+	}
+	if res.StatusCode != http.StatusOK {
+		jc.printTrace("Restoring cache from object storage failed, using the local cache: " + res.Status)
+		return
+	}
+
+	var staging = jc.cacheDir + ".restore"
+	os.RemoveAll(staging)
+
+	if err = extractArchive(res.Body, staging); err != nil {
+		os.RemoveAll(staging)
+		jc.printTrace("Extracting shared cache failed, using the local cache: " + err.Error())
+		return
+	}
+
+	os.RemoveAll(jc.cacheDir)
+	if err = os.Rename(staging, jc.cacheDir); err != nil {
+		os.MkdirAll(jc.cacheDir, 0700)
+		jc.printTrace("Replacing local cache failed: " + err.Error())
+		return
+	}
+
+	jc.printTrace("Restored shared cache from object storage")
+}
+
+func (jc *JobContext) saveCache() {
+
+	var storage = config.CacheStorage
+	if storage.Endpoint == "" {
+		return
+	}
+
+	var f, err = os.CreateTemp(config.WorkDir, ".cache-upload-")
+	if err != nil {
+		jc.printTrace("Saving cache to object storage failed: " + err.Error())
+		return
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	var hash = sha256.New()
+	if err = createArchive(io.MultiWriter(f, hash), jc.cacheDir); err == nil {
+		_, err = f.Seek(0, io.SeekStart)
+	}
+
+	var info os.FileInfo
+	if err == nil {
+		info, err = f.Stat()
+	}
+
+	var req *http.Request
+	if err == nil {
+		req, err = http.NewRequest(http.MethodPut, storage.objectURL(jc.projID+".tar.gz"), f)
+	}
+	if err != nil {
+		jc.printTrace("Saving cache to object storage failed: " + err.Error())
+		return
+	}
+
+	req.ContentLength = info.Size()
+	req.Header.Set("Content-Type", "application/gzip")
+	storage.sign(req, hex.EncodeToString(hash.Sum(nil)))
+
+	var res *http.Response
+	if res, err = storageClient.Do(req); err != nil {
+		jc.printTrace("Saving cache to object storage failed: " + err.Error())
+		return
+	}
+	io.Copy(io.Discard, res.Body)
+	res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		jc.printTrace("Saving cache to object storage failed: " + res.Status)
+		return
+	}
+
+	jc.printTrace("Saved shared cache to object storage")
+}
+
+func (s ObjectStorage) sign(req *http.Request, payloadHash string) {
+
+	var now = time.Now().UTC()
+	var amzDate = now.Format("20060102T150405Z")
+	var date = amzDate[:8]
+	var region = s.Region
+	if region == "" {
+		region = "us-east-1"
+	}
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	var signedHeaders = "host;x-amz-content-sha256;x-amz-date"
+	var canonical = strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.RawQuery,
+		"host:" + req.URL.Host,
+		"x-amz-content-sha256:" + payloadHash,
+		"x-amz-date:" + amzDate,
+		"",
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	var scope = date + "/" + region + "/s3/aws4_request"
+	var canonicalHash = sha256.Sum256([]byte(canonical))
+	var toSign = "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(canonicalHash[:])
+
+	var key = []byte("AWS4" + s.SecretKey)
+	for _, val := range []string{date, region, "s3", "aws4_request"} {
+		key = hmacSHA256(key, val)
+	}
+
+	req.Header.Set("Authorization", "AWS4-HMAC-SHA256 Credential="+s.AccessKey+"/"+scope+
+		", SignedHeaders="+signedHeaders+", Signature="+hex.EncodeToString(hmacSHA256(key, toSign)))
+}
+
+func isEmptyDir(dir string) bool {
+
+	var list, _ = os.ReadDir(dir)
+	return len(list) == 0
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+
+	var mac = hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+func createArchive(w io.Writer, dir string) error {
+
+	var zw = gzip.NewWriter(w)
+	var tw = tar.NewWriter(zw)
+
+	var err = filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+
+		if err != nil || path == dir {
+			return err
+		}
+
+		var info, _ = entry.Info()
+		if info == nil {
+			return nil
+		}
+
+		var link string
+		if info.Mode()&fs.ModeSymlink != 0 {
+			if link, err = os.Readlink(path); err != nil {
+				return err
+			}
+		} else if !info.Mode().IsRegular() && !info.IsDir() {
+			return nil
+		}
+
+		var header *tar.Header
+		if header, err = tar.FileInfoHeader(info, link); err != nil {
+			return err
+		}
+		header.Name, _ = filepath.Rel(dir, path)
+		header.Name = filepath.ToSlash(header.Name)
+
+		if err = tw.WriteHeader(header); err != nil || !info.Mode().IsRegular() {
+			return err
+		}
+
+		var f *os.File
+		if f, err = os.Open(path); err != nil {
+			return err
+		}
+		_, err = io.Copy(tw, f)
+		f.Close()
+		return err
+	})
+
+	if err == nil {
+		err = tw.Close()
+	}
+	if err == nil {
+		err = zw.Close()
+	}
+	return err
+}
+
+func extractArchive(r io.Reader, dir string) error {
+
+	var zr, err = gzip.NewReader(r)
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+
+	var tr = tar.NewReader(zr)
+	for {
+		var header *tar.Header
+		if header, err = tr.Next(); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		var name = filepath.Join(dir, filepath.FromSlash(header.Name))
+		if !strings.HasPrefix(name, dir+string(filepath.Separator)) {
+			return errors.New("archive entry " + header.Name + " is outside of the cache directory")
+		}
+
+		switch header.Typeflag {
+		case tar.TypeDir:
+			err = os.MkdirAll(name, header.FileInfo().Mode().Perm()|0700)
+
+		case tar.TypeSymlink:
+			var target = filepath.Join(filepath.Dir(name), header.Linkname)
+			if filepath.IsAbs(header.Linkname) || !strings.HasPrefix(target, dir+string(filepath.Separator)) {
+				return errors.New("archive link " + header.Name + " points outside of the cache directory")
+			}
+			os.MkdirAll(filepath.Dir(name), 0700)
+			err = os.Symlink(header.Linkname, name)
+
+		case tar.TypeReg:
+			os.MkdirAll(filepath.Dir(name), 0700)
+			var f *os.File
+			if f, err = os.OpenFile(name, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, header.FileInfo().Mode().Perm()); err == nil {
+				_, err = io.Copy(f, tr)
+				f.Close()
+			}
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+}
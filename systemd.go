@@ -0,0 +1,92 @@
+package main
+
+import (
+	"net"
+	"os"
+	"os/signal"
+	"strconv"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+var isStopping atomic.Bool
+
+func notify(state string) {
+
+	var socket = os.Getenv("NOTIFY_SOCKET")
+	if socket == "" {
+		return
+	}
+
+	if socket[0] == '@' {
+		socket = "\x00" + socket[1:]
+	}
+
+	var conn, err = net.DialUnix("unixgram", nil, &net.UnixAddr{Name: socket, Net: "unixgram"})
+	if err != nil {
+		return
+	}
+
+	conn.Write([]byte(state))
+	conn.Close()
+}
+
+func watchdogInterval() time.Duration {
+
+	var usec, err = strconv.ParseInt(os.Getenv("WATCHDOG_USEC"), 10, 64)
+	if err != nil || usec <= 0 {
+		return 0
+	}
+
+	return time.Duration(usec) * time.Microsecond / 2
+}
+
+func keepAlive() (stop func()) {
+
+	var interval = watchdogInterval()
+	if interval == 0 {
+		return func() {}
+	}
+
+	var done = make(chan struct{})
+	go func() {
+		var ticker = time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				notify("WATCHDOG=1")
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	return func() { close(done) }
+}
+
+func handleSignals() {
+
+	var signals = make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	if pauseSignal != nil {
+		signal.Notify(signals, pauseSignal, upgradeSignal)
+	}
+
+	go func() {
+		for sig := range signals {
+			switch sig {
+			case pauseSignal:
+				isPaused.Store(!isPaused.Load())
+				continue
+			case upgradeSignal:
+				isUpgrading.Store(true)
+			default:
+				notify("STOPPING=1")
+			}
+			isStopping.Store(true)
+		}
+	}()
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"strings"
+)
+
+func loadToken() {
+
+	if config.Token != "" || len(config.TokenCommand) == 0 {
+		return
+	}
+
+	var cmd = exec.Command(config.TokenCommand[0], config.TokenCommand[1:]...)
+	cmd.Stderr = os.Stderr
+
+	var data, err = cmd.Output()
+	if err != nil {
+		printErr("Reading runner token with " + config.TokenCommand[0] + " failed: " + err.Error())
+	}
+
+	if config.Token = strings.TrimSpace(string(data)); config.Token == "" {
+		printErr("Token command " + config.TokenCommand[0] + " returned an empty token")
+	}
+}
+
+func storeToken(args []string, token string) error {
+
+	var cmd = exec.Command(args[0], args[1:]...)
+	cmd.Stdin = strings.NewReader(token)
+	cmd.Stdout = os.Stderr
+	cmd.Stderr = os.Stderr
+
+	return cmd.Run()
+}
@@ -0,0 +1,355 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type traceProcessor func(jc *JobContext, next io.WriteCloser) io.WriteCloser
+
+var traceProcessors = map[string]traceProcessor{
+	"sanitize":   newSanitizeWriter,
+	"coverage":   newCoverageWriter,
+	"timestamps": newTimestampWriter,
+	"mask":       newMaskWriter,
+	"limit":      newLimitWriter,
+	"collapse":   newCollapseWriter,
+}
+
+var defaultTraceProcessors = []string{"sanitize", "coverage", "timestamps", "limit"}
+
+const maxLineLength = 64 << 10
+
+var coverageNumber = regexp.MustCompile(`\d+(\.\d+)?`)
+
+func newTracePipeline(jc *JobContext, sink io.Writer) io.WriteCloser {
+
+	var names = config.Trace
+	if names == nil {
+		names = defaultTraceProcessors
+	}
+
+	var w io.WriteCloser = nopCloser{sink}
+	for i := len(names) - 1; i >= 0; i-- {
+		if names[i] != "mask" {
+			w = traceProcessors[names[i]](jc, w)
+		}
+	}
+
+	return newMaskWriter(jc, w)
+}
+
+func checkTraceProcessors() {
+
+	for _, name := range config.Trace {
+		if traceProcessors[name] == nil {
+			printErr("Unknown trace processor: " + name)
+		}
+	}
+}
+
+type nopCloser struct {
+	io.Writer
+}
+
+func (nopCloser) Close() error {
+	return nil
+}
+
+type lineWriter struct {
+	next io.WriteCloser
+	line []byte
+	fn   func(line []byte) []byte
+}
+
+func (w *lineWriter) Write(p []byte) (int, error) {
+
+	var n = len(p)
+	for {
+		var i = bytes.IndexByte(p, '\n')
+		if i < 0 && len(w.line)+len(p) < maxLineLength {
+			w.line = append(w.line, p...)
+			return n, nil
+		}
+
+		if i < 0 {
+			i = maxLineLength - len(w.line) - 1
+			if i < 0 {
+				i = 0
+			}
+		}
+
+		w.line = append(w.line, p[:i+1]...)
+		if _, err := w.next.Write(w.fn(w.line)); err != nil {
+			return n, err
+		}
+
+		w.line = w.line[:0]
+		p = p[i+1:]
+	}
+}
+
+func (w *lineWriter) Close() error {
+
+	if len(w.line) > 0 {
+		w.next.Write(w.fn(w.line))
+		w.line = w.line[:0]
+	}
+
+	return w.next.Close()
+}
+
+func newMaskWriter(jc *JobContext, next io.WriteCloser) io.WriteCloser {
+
+	return &lineWriter{next: next, fn: jc.maskLine}
+}
+
+func (jc *JobContext) maskLine(line []byte) []byte {
+
+	for _, val := range jc.job.Variables {
+		if val.Masked && val.Value != "" {
+			line = bytes.ReplaceAll(line, []byte(val.Value), []byte("[MASKED]"))
+		}
+	}
+	return line
+}
+
+func newSanitizeWriter(jc *JobContext, next io.WriteCloser) io.WriteCloser {
+	return &lineWriter{next: next, fn: sanitizeLine}
+}
+
+func sanitizeLine(line []byte) []byte {
+
+	var body = bytes.TrimSuffix(line, []byte("\n"))
+	var hasNewline = len(body) < len(line)
+
+	body = bytes.TrimSuffix(body, []byte("\r"))
+	if i := bytes.LastIndexByte(body, '\r'); i >= 0 {
+		body = body[i+1:]
+	}
+
+	var out = make([]byte, 0, len(body)+1)
+	for i := 0; i < len(body); i++ {
+
+		var c = body[i]
+		if c == 0x1b && i+1 < len(body) && body[i+1] == '[' {
+			var j = i + 2
+			for j < len(body) && body[j] >= 0x20 && body[j] <= 0x3f {
+				j++
+			}
+			if j < len(body) && body[j] == 'm' {
+				out = append(out, body[i:j+1]...)
+			}
+			i = j
+			continue
+		}
+
+		if c == 0x1b && i+1 < len(body) && body[i+1] == ']' {
+			var j = i + 2
+			for j < len(body) && body[j] != 0x07 && body[j] != 0x1b {
+				j++
+			}
+			if j+1 < len(body) && body[j] == 0x1b && body[j+1] == '\\' {
+				j++
+			}
+			i = j
+			continue
+		}
+
+		if c == 0x1b {
+			i++
+			continue
+		}
+
+		if c < 0x20 && c != '\t' || c == 0x7f {
+			continue
+		}
+
+		out = append(out, c)
+	}
+
+	if hasNewline {
+		out = append(out, '\n')
+	}
+
+	return out
+}
+
+func newCoverageWriter(jc *JobContext, next io.WriteCloser) io.WriteCloser {
+
+	var pattern = config.CoverageRegex
+	if value := jc.variable("COVERAGE_REGEX"); value != "" {
+		pattern = value
+	}
+
+	var re, err = regexp.Compile(strings.Trim(pattern, "/"))
+	if pattern == "" || err != nil {
+		return next
+	}
+
+	return &lineWriter{next: next, fn: func(line []byte) []byte {
+
+		var match = re.FindSubmatch(line)
+		if match == nil {
+			return line
+		}
+
+		var text = match[0]
+		if len(match) > 1 {
+			text = match[1]
+		}
+
+		if value, err := strconv.ParseFloat(string(coverageNumber.Find(text)), 64); err == nil {
+			jc.coverage = value
+		}
+		return line
+	}}
+}
+
+type collapseWriter struct {
+	*lineWriter
+	last    []byte
+	repeats int
+}
+
+func newCollapseWriter(jc *JobContext, next io.WriteCloser) io.WriteCloser {
+
+	var w = &collapseWriter{}
+	w.lineWriter = &lineWriter{next: next, fn: w.collapse}
+	return w
+}
+
+func (w *collapseWriter) collapse(line []byte) []byte {
+
+	if len(line) > 1 && line[len(line)-1] == '\n' && bytes.Equal(line, w.last) {
+		w.repeats++
+		return nil
+	}
+
+	var out = append(w.repeated(), line...)
+	w.last = append(w.last[:0], line...)
+	return out
+}
+
+func (w *collapseWriter) repeated() []byte {
+
+	if w.repeats == 0 {
+		return nil
+	}
+
+	var marker = "(repeated " + strconv.Itoa(w.repeats+1) + " times)\n"
+	w.repeats = 0
+	return []byte(marker)
+}
+
+func (w *collapseWriter) Close() error {
+
+	if len(w.line) > 0 {
+		w.next.Write(w.collapse(w.line))
+		w.line = w.line[:0]
+	}
+	w.next.Write(w.repeated())
+
+	return w.next.Close()
+}
+
+type limitWriter struct {
+	jc      *JobContext
+	next    io.WriteCloser
+	left    int
+	limited bool
+}
+
+func newLimitWriter(jc *JobContext, next io.WriteCloser) io.WriteCloser {
+
+	if config.TraceLimit <= 0 {
+		return next
+	}
+
+	return &limitWriter{jc: jc, next: next, left: config.TraceLimit}
+}
+
+func (w *limitWriter) Write(p []byte) (int, error) {
+
+	if w.limited {
+		return len(p), nil
+	}
+
+	if w.jc.isDebugTrace {
+		return w.next.Write(p)
+	}
+
+	if len(p) <= w.left {
+		w.left -= len(p)
+		return w.next.Write(p)
+	}
+
+	w.limited = true
+	w.next.Write(p[:w.left])
+	w.next.Write([]byte("\nJob's log exceeded limit of " + strconv.Itoa(config.TraceLimit) + " bytes.\n"))
+
+	return len(p), nil
+}
+
+func (w *limitWriter) Close() error {
+	return w.next.Close()
+}
+
+type timestampWriter struct {
+	next      io.WriteCloser
+	start     time.Time
+	isMidLine bool
+}
+
+func newTimestampWriter(jc *JobContext, next io.WriteCloser) io.WriteCloser {
+
+	if config.Timestamps != "rfc3339" && config.Timestamps != "relative" {
+		return next
+	}
+
+	return &timestampWriter{next: next, start: time.Now()}
+}
+
+func (w *timestampWriter) Write(p []byte) (int, error) {
+
+	var n = len(p)
+	for len(p) > 0 {
+
+		if !w.isMidLine {
+			if _, err := w.next.Write([]byte(w.timestamp())); err != nil {
+				return n, err
+			}
+		}
+
+		var i = bytes.IndexByte(p, '\n') + 1
+		if i == 0 {
+			i = len(p)
+		}
+
+		if _, err := w.next.Write(p[:i]); err != nil {
+			return n, err
+		}
+
+		w.isMidLine = p[i-1] != '\n'
+		p = p[i:]
+	}
+
+	return n, nil
+}
+
+func (w *timestampWriter) timestamp() string {
+
+	if config.Timestamps == "relative" {
+		return time.Time{}.Add(time.Since(w.start)).Format("15:04:05.000") + " "
+	}
+
+	return time.Now().UTC().Format(time.RFC3339) + " "
+}
+
+func (w *timestampWriter) Close() error {
+	return w.next.Close()
+}
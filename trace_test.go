@@ -0,0 +1,46 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestMaskWriter(t *testing.T) {
+
+	var job = &Job{Variables: []Variable{
+		{Key: "TOKEN", Value: "s3cr3t", Masked: true},
+		{Key: "PUBLIC", Value: "visible"},
+	}}
+
+	for _, trace := range [][]string{nil, {"timestamps"}, {"sanitize", "limit"}} {
+
+		config = Config{Trace: trace}
+		var jc = newJobContext(job, nil)
+		var out bytes.Buffer
+		var w = newTracePipeline(jc, &out)
+
+		w.Write([]byte("token=s3c"))
+		w.Write([]byte("r3t visible\nlast s3cr3t"))
+		w.Close()
+
+		if bytes.Contains(out.Bytes(), []byte("s3cr3t")) {
+			t.Errorf("Trace %q leaked the masked value: %q", trace, out.String())
+		}
+		if bytes.Count(out.Bytes(), []byte("[MASKED]")) != 2 || !bytes.Contains(out.Bytes(), []byte("visible")) {
+			t.Errorf("Trace %q = %q", trace, out.String())
+		}
+	}
+}
+
+func TestCollapseWriter(t *testing.T) {
+
+	var out bytes.Buffer
+	var w = newCollapseWriter(nil, nopCloser{&out})
+	w.Write([]byte("a\na\na\n\n\nb\nb\nc"))
+	w.Close()
+
+	var want = "a\n(repeated 3 times)\n\n\nb\n(repeated 2 times)\nc"
+	if out.String() != want {
+		t.Errorf("collapse = %q, want %q", out.String(), want)
+	}
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+)
+
+func traceLogDir() string {
+	return config.WorkDir + "/logs"
+}
+
+func (jc *JobContext) createTraceLog() {
+
+	if !config.TraceLogs {
+		return
+	}
+
+	var dir = traceLogDir() + "/" + jc.projID
+	var err = os.MkdirAll(dir, 0700)
+	if err == nil {
+		jc.traceLog, err = os.OpenFile(dir+"/"+jc.jobID+".log", os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+	}
+	if err != nil {
+		printLog("Creating trace log for job " + jc.jobID + " failed: " + err.Error())
+	}
+}
+
+func (jc *JobContext) closeTraceLog() {
+
+	if jc.traceLog != nil {
+		jc.traceLog.Close()
+		jc.traceLog = nil
+	}
+}
+
+func cleanTraceLogs() {
+
+	if config.TraceLogRetentionDays <= 0 {
+		return
+	}
+
+	var deadline = time.Now().AddDate(0, 0, -config.TraceLogRetentionDays)
+	var names, _ = filepath.Glob(traceLogDir() + "/*/*.log")
+
+	for _, name := range names {
+		if info, err := os.Stat(name); err == nil && info.ModTime().Before(deadline) {
+			os.Remove(name)
+		}
+	}
+}
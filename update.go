@@ -0,0 +1,79 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/neo-mode/runner-api"
+)
+
+const updateRetries = 5
+
+func updateJob(id string, state State) (string, error) {
+
+	var data, err = json.Marshal(state)
+	if err != nil {
+		return "", err
+	}
+
+	var req *http.Request
+	req, err = http.NewRequest(http.MethodPut, apiURL("/jobs/"+id), bytes.NewReader(data))
+	if err != nil {
+		return "", err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	var res *http.Response
+	if res, err = runner.Client.Do(req); err != nil {
+		return "", err
+	}
+
+	io.Copy(io.Discard, res.Body)
+	res.Body.Close()
+
+	var status = res.Header.Get("Job-Status")
+	switch {
+	case res.StatusCode == http.StatusOK || res.StatusCode == http.StatusAccepted:
+		return status, nil
+
+	case res.StatusCode == http.StatusTooManyRequests || res.StatusCode >= 500:
+		return status, errors.New(res.Status)
+	}
+
+	return status, runner.APIError(res.Status)
+}
+
+func sendUpdate(id string, state State) error {
+
+	var err error
+	for attempt := 0; attempt <= updateRetries; attempt++ {
+
+		if attempt > 0 {
+			time.Sleep(time.Duration(attempt) * time.Second)
+			waitRateLimit()
+		}
+
+		if _, err = updateJob(id, state); err == nil {
+			return nil
+		}
+
+		if _, ok := err.(runner.APIError); ok {
+			return err
+		}
+	}
+
+	return err
+}
+
+func isRemotelyFinished(status string, err error) bool {
+
+	if _, ok := err.(runner.APIError); ok {
+		return true
+	}
+	return status == "canceled" || status == "canceling" || status == "failed"
+}
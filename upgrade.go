@@ -0,0 +1,28 @@
+package main
+
+import (
+	"os"
+	"strings"
+	"sync/atomic"
+)
+
+var isUpgrading atomic.Bool
+
+func reexec() {
+
+	var path, err = os.Executable()
+	if err != nil {
+		printErr(err.Error())
+	}
+	path = strings.TrimSuffix(path, " (deleted)")
+
+	notify("RELOADING=1")
+
+	var args = []string{os.Args[0], "--config", configFile}
+	if dryRunFlag {
+		args = append(args, "--dry-run")
+	}
+
+	err = execSelf(path, args, startupEnv)
+	printErr(err.Error())
+}
@@ -0,0 +1,9 @@
+//go:build unix
+
+package main
+
+import "syscall"
+
+func execSelf(path string, args []string, env []string) error {
+	return syscall.Exec(path, args, env)
+}
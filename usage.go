@@ -0,0 +1,177 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"sort"
+	"strconv"
+	"text/tabwriter"
+	"time"
+)
+
+const usageRetentionDays = 62
+
+const usageDay = "2006-01-02"
+
+type MinutesQuota struct {
+	Daily   float64
+	Monthly float64
+}
+
+type MinutesQuotaError string
+
+type ProjectUsage struct {
+	Jobs     int                `json:"jobs"`
+	Duration float64            `json:"duration"`
+	CPUTime  float64            `json:"cpu_time"`
+	Days     map[string]float64 `json:"days"`
+}
+
+func usageFile() string {
+	return config.WorkDir + "/.usage.json"
+}
+
+func loadUsage() map[string]*ProjectUsage {
+
+	var usage = map[string]*ProjectUsage{}
+	if data, err := os.ReadFile(usageFile()); err == nil {
+		json.Unmarshal(data, &usage)
+	}
+	return usage
+}
+
+func updateUsage(fn func(usage map[string]*ProjectUsage)) error {
+
+	var lock, err = os.OpenFile(config.WorkDir+"/.usage.lock", os.O_CREATE|os.O_RDWR, 0600)
+	if err != nil {
+		return err
+	}
+	defer lock.Close()
+
+	if err = lockFile(lock, true); err != nil {
+		return err
+	}
+
+	var usage = loadUsage()
+	fn(usage)
+
+	var deadline = time.Now().AddDate(0, 0, -usageRetentionDays).Format(usageDay)
+	for _, val := range usage {
+		for day := range val.Days {
+			if day < deadline {
+				delete(val.Days, day)
+			}
+		}
+	}
+
+	var data []byte
+	if data, err = json.Marshal(usage); err != nil {
+		return err
+	}
+
+	var name = usageFile()
+	if err = os.WriteFile(name+".tmp", data, 0600); err == nil {
+		err = os.Rename(name+".tmp", name)
+	}
+	return err
+}
+
+func recordUsage(result JobResult) {
+
+	var err = updateUsage(func(usage map[string]*ProjectUsage) {
+
+		var project = usage[result.ProjectID]
+		if project == nil {
+			project = &ProjectUsage{}
+			usage[result.ProjectID] = project
+		}
+		if project.Days == nil {
+			project.Days = map[string]float64{}
+		}
+
+		project.Jobs++
+		project.Duration += result.Duration
+		project.CPUTime += result.CPUTime
+		project.Days[result.StartedAt.Format(usageDay)] += result.Duration
+	})
+
+	if err != nil {
+		printLog("Recording job usage failed: " + err.Error())
+	}
+}
+
+func (usage *ProjectUsage) minutesSince(since time.Time) float64 {
+
+	var first = since.Format(usageDay)
+	var total float64
+	for day, val := range usage.Days {
+		if day >= first {
+			total += val
+		}
+	}
+	return total / 60
+}
+
+func usagePeriods(now time.Time) (today time.Time, month time.Time) {
+	return time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local), time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.Local)
+}
+
+func (jc *JobContext) checkMinutesQuota() error {
+
+	var quota, ok = config.MinutesQuotas[jc.projID]
+	if !ok {
+		quota, ok = config.MinutesQuotas["*"]
+	}
+	var usage = loadUsage()[jc.projID]
+	if !ok || usage == nil {
+		return nil
+	}
+
+	var today, month = usagePeriods(time.Now())
+	if used := usage.minutesSince(today); quota.Daily > 0 && used >= quota.Daily {
+		return MinutesQuotaError("Project " + jc.projID + " has used " + formatMinutes(used) + " of its " + formatMinutes(quota.Daily) + " daily job minutes on this runner, jobs run again tomorrow")
+	}
+	if used := usage.minutesSince(month); quota.Monthly > 0 && used >= quota.Monthly {
+		return MinutesQuotaError("Project " + jc.projID + " has used " + formatMinutes(used) + " of its " + formatMinutes(quota.Monthly) + " monthly job minutes on this runner, jobs run again next month")
+	}
+	return nil
+}
+
+func printStats() {
+
+	var w = tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	var today, month = usagePeriods(time.Now())
+
+	for i := range runnerConfigs {
+
+		useRunner(i)
+		if len(runnerConfigs) > 1 {
+			w.Write([]byte("Runner " + strconv.Itoa(i+1) + " (" + config.URL + ")\n"))
+		}
+		w.Write([]byte("PROJECT\tJOBS\tMINUTES\tCPU MINUTES\tTODAY\tTHIS MONTH\n"))
+
+		var usage = loadUsage()
+		var projects []string
+		for key := range usage {
+			projects = append(projects, key)
+		}
+		sort.Strings(projects)
+
+		for _, key := range projects {
+			var val = usage[key]
+			w.Write([]byte(key + "\t" + strconv.Itoa(val.Jobs) + "\t" + formatMinutes(val.Duration/60) + "\t" + formatMinutes(val.CPUTime/60) + "\t" +
+				formatMinutes(val.minutesSince(today)) + "\t" + formatMinutes(val.minutesSince(month)) + "\n"))
+		}
+	}
+
+	w.Flush()
+	os.Exit(0)
+}
+
+func formatMinutes(minutes float64) string {
+	return strconv.FormatFloat(minutes, 'f', 1, 64)
+}
+
+func (err MinutesQuotaError) Error() string {
+	return string(err)
+}
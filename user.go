@@ -0,0 +1,161 @@
+package main
+
+import (
+	"io/fs"
+	"os"
+	"os/exec"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+type UserError string
+
+type jobUser struct {
+	name       string
+	home       string
+	credential *userCredential
+}
+
+type userCredential struct {
+	Uid    uint32
+	Gid    uint32
+	Groups []uint32
+}
+
+func checkUsers() {
+
+	var names = []string{config.User}
+	for _, val := range config.Jobs {
+		names = append(names, val.User)
+	}
+
+	for _, name := range names {
+		if name == "" {
+			continue
+		}
+		if _, err := lookupUser(name); err != nil {
+			printErr("Invalid job user " + name + ": " + err.Error())
+		}
+		if os.Geteuid() != 0 {
+			printErr("Running jobs as user " + name + " requires the runner to run as root")
+		}
+	}
+}
+
+func lookupUser(name string) (*jobUser, error) {
+
+	var account, err = user.Lookup(name)
+	if err != nil {
+		if numeric, ok := parseNumericUser(name); ok {
+			return numeric, nil
+		}
+		return nil, err
+	}
+
+	var uid, gid uint64
+	if uid, err = strconv.ParseUint(account.Uid, 10, 32); err != nil {
+		return nil, err
+	}
+	if gid, err = strconv.ParseUint(account.Gid, 10, 32); err != nil {
+		return nil, err
+	}
+
+	var credential = &userCredential{Uid: uint32(uid), Gid: uint32(gid)}
+	var groups, _ = account.GroupIds()
+	for _, val := range groups {
+		if id, err := strconv.ParseUint(val, 10, 32); err == nil {
+			credential.Groups = append(credential.Groups, uint32(id))
+		}
+	}
+
+	return &jobUser{name: account.Username, home: account.HomeDir, credential: credential}, nil
+}
+
+func (jc *JobContext) defineUser(configJob *ConfigJob) error {
+
+	var name = config.User
+	if configJob != nil && configJob.User != "" {
+		name = configJob.User
+	}
+	if name == "" {
+		return nil
+	}
+
+	var err error
+	if jc.user, err = lookupUser(name); err != nil {
+		return UserError("Looking up job user " + name + " failed: " + err.Error())
+	}
+
+	jc.setEnv("HOME", jc.user.home)
+	jc.setEnv("USER", jc.user.name)
+	jc.setEnv("LOGNAME", jc.user.name)
+
+	return nil
+}
+
+func (jc *JobContext) chownLayout() error {
+
+	if jc.user == nil {
+		return nil
+	}
+
+	var uid, gid = int(jc.user.credential.Uid), int(jc.user.credential.Gid)
+	for _, dir := range []string{jc.projDir, jc.cacheDir, jc.tmpDir} {
+
+		for parent := filepath.Dir(dir); strings.HasPrefix(parent, config.WorkDir); parent = filepath.Dir(parent) {
+			if info, err := os.Stat(parent); err == nil {
+				os.Chmod(parent, info.Mode().Perm()|0011)
+			}
+		}
+
+		if dir == jc.projDir && jc.isOwnedByUser(dir) {
+			continue
+		}
+
+		var err = filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			return os.Lchown(path, uid, gid)
+		})
+		if err != nil && !os.IsNotExist(err) {
+			return UserError("Changing ownership of " + dir + " to " + jc.user.name + " failed: " + err.Error())
+		}
+	}
+
+	return nil
+}
+
+func (jc *JobContext) isOwnedByUser(path string) bool {
+
+	var info, err = os.Lstat(path)
+	if err != nil {
+		return false
+	}
+
+	var uid, ok = fileOwner(info)
+	return ok && uid == jc.user.credential.Uid
+}
+
+func (jc *JobContext) makeUserDir(dir string) error {
+
+	if err := os.MkdirAll(dir, 0755); err != nil || jc.user == nil {
+		return err
+	}
+	return os.Lchown(dir, int(jc.user.credential.Uid), int(jc.user.credential.Gid))
+}
+
+func (jc *JobContext) applyUser(cmd *exec.Cmd) {
+
+	if jc.user == nil {
+		return
+	}
+
+	setCredential(cmd, jc.user.credential)
+}
+
+func (err UserError) Error() string {
+	return string(err)
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"os/exec"
+	"time"
+)
+
+const webhookTimeout = 10 * time.Second
+
+type Webhook struct {
+	URL  string
+	Exec []string
+	On   string
+}
+
+func fireWebhooks(result JobResult) {
+
+	if len(config.Webhooks) == 0 {
+		return
+	}
+
+	var data, err = json.Marshal(result)
+	if err != nil {
+		return
+	}
+
+	for _, hook := range config.Webhooks {
+
+		if hook.On == "success" && result.State != "success" || hook.On == "failure" && result.State == "success" {
+			continue
+		}
+
+		if err = callWebhook(hook, data); err != nil {
+			printLog("Webhook for job " + result.ID + " failed: " + err.Error())
+		}
+	}
+}
+
+func callWebhook(hook Webhook, data []byte) error {
+
+	var ctx, cancel = context.WithTimeout(context.Background(), webhookTimeout)
+	defer cancel()
+
+	if len(hook.Exec) > 0 {
+		var cmd = exec.CommandContext(ctx, hook.Exec[0], hook.Exec[1:]...)
+		cmd.Stdin = bytes.NewReader(data)
+		return cmd.Run()
+	}
+
+	var req, err = http.NewRequestWithContext(ctx, http.MethodPost, hook.URL, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	var res *http.Response
+	if res, err = http.DefaultClient.Do(req); err != nil {
+		return err
+	}
+
+	io.Copy(io.Discard, res.Body)
+	res.Body.Close()
+
+	if res.StatusCode >= 300 {
+		return errors.New(res.Status)
+	}
+	return nil
+}
+
+func checkWebhooks() {
+
+	for _, hook := range config.Webhooks {
+		if hook.URL == "" && len(hook.Exec) == 0 {
+			printErr("Webhook needs either URL or Exec")
+		}
+		if hook.On != "" && hook.On != "success" && hook.On != "failure" {
+			printErr("Invalid webhook condition: " + hook.On)
+		}
+	}
+}
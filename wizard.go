@@ -0,0 +1,155 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/neo-mode/runner-api"
+)
+
+func promptURL(value *string) {
+
+	for {
+		prompt("Input GitLab URL", value, defaultURL)
+
+		var err = checkInstance(*value)
+		if err == nil {
+			return
+		}
+
+		println("Cannot use " + *value + ": " + err.Error())
+		*value = ""
+	}
+}
+
+func checkInstance(text string) error {
+
+	if _, err := parseBaseURL(text); err != nil {
+		return err
+	}
+
+	config.URL = text
+	if config.ConnectionTimeout == 0 {
+		config.ConnectionTimeout = 10
+	}
+	defineClient()
+
+	var res, err = runner.Client.Get(apiURL("/version"))
+	if urlErr, ok := err.(*url.Error); ok {
+		return urlErr.Err
+	}
+	if err != nil {
+		return err
+	}
+	res.Body.Close()
+
+	if res.StatusCode != http.StatusOK && res.StatusCode != http.StatusUnauthorized {
+		return errors.New("no GitLab API found, the server responded with " + res.Status)
+	}
+	return nil
+}
+
+func promptSetup() {
+
+	for config.WorkDir == "" {
+		var workDir string
+		prompt("Input work directory", &workDir, defaultWorkDir())
+
+		config.WorkDir = workDir
+		if _, err := checkWorkDir(); err != nil {
+			println("Cannot use " + workDir + ": " + err.Error())
+			config.WorkDir = ""
+		}
+	}
+
+	var found = detectShells()
+	var def = "sh"
+	if len(found) > 0 && found[0] != "sh" {
+		def = found[0]
+	}
+
+	for config.Shell == "" {
+		var shell string
+		prompt("Input shell ("+strings.Join(found, ", ")+")", &shell, def)
+
+		var _, ok = shells[shell]
+		if _, err := exec.LookPath(shell); ok && err == nil {
+			config.Shell = shell
+			continue
+		}
+		println("Shell " + shell + " is not available")
+	}
+}
+
+func detectShells() []string {
+
+	var found []string
+	for name := range shells {
+		if _, err := exec.LookPath(name); err == nil {
+			found = append(found, name)
+		}
+	}
+
+	sort.Slice(found, func(i, j int) bool {
+		return found[i] == "sh" || found[j] != "sh" && found[i] < found[j]
+	})
+	return found
+}
+
+func finishSetup() {
+
+	var serverTime time.Time
+	var checks = []doctorCheck{
+		{"GitLab API", func() (string, error) { return checkAPI(&serverTime) }},
+		{"Git", checkGit},
+		{"Smoke test job", runSmokeJob},
+	}
+
+	println("Running a smoke test job")
+	if !runChecks(checks) {
+		printErr("Smoke test failed, fix the problems above and check again with: runner doctor")
+	}
+
+	var answer string
+	prompt("Install and start the runner as a service? (yes/no)", &answer, "no")
+	if answer != "yes" && answer != "y" {
+		println("Runner is ready")
+		return
+	}
+
+	var manager = defineServiceManager(defaultServiceName, "")
+	installService(defaultServiceName, manager)
+	runCommands(manager.start)
+
+	println("Runner is ready and running as service " + defaultServiceName)
+	os.Exit(0)
+}
+
+func runSmokeJob() (string, error) {
+
+	var dir, err = os.MkdirTemp(config.WorkDir, ".smoke-")
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(dir)
+
+	var jc = &JobContext{shell: config.Shell}
+	var name, args, stdin = jc.shellCommand([]string{"git init -q", "git status --short"})
+
+	var cmd = exec.Command(name, args...)
+	cmd.Dir = dir
+	cmd.Stdin = strings.NewReader(strings.Join(stdin, "\n") + "\n")
+
+	var output []byte
+	if output, err = cmd.CombinedOutput(); err != nil {
+		return "", errors.New("script failed in " + config.Shell + ": " + strings.TrimSpace(string(output)))
+	}
+
+	return "script ran in " + config.Shell + " in " + config.WorkDir, nil
+}
@@ -0,0 +1,231 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+)
+
+type yamlParser struct {
+	lines []string
+	pos   int
+}
+
+func parseYAML(data string) map[string]any {
+
+	var p = &yamlParser{lines: strings.Split(strings.TrimSuffix(strings.ReplaceAll(data, "\r\n", "\n"), "\n"), "\n")}
+	var indent, _, ok = p.peek()
+	if !ok {
+		return map[string]any{}
+	}
+
+	var m, _ = p.parseMap(indent).(map[string]any)
+	return m
+}
+
+func (p *yamlParser) peek() (int, string, bool) {
+
+	for ; p.pos < len(p.lines); p.pos++ {
+
+		var line = strings.TrimRight(p.lines[p.pos], " \t")
+		var text = strings.TrimLeft(line, " ")
+		if text == "" || text[0] == '#' || text == "---" {
+			continue
+		}
+
+		return len(line) - len(text), text, true
+	}
+
+	return 0, "", false
+}
+
+func (p *yamlParser) parseMap(indent int) any {
+
+	var m = map[string]any{}
+	for {
+		var ind, text, ok = p.peek()
+		if !ok || ind != indent || isSeqItem(text) {
+			return m
+		}
+
+		p.pos++
+		var key, rest = splitKey(text)
+		m[key] = p.parseValue(indent, rest, true)
+	}
+}
+
+func (p *yamlParser) parseSeq(indent int) any {
+
+	var seq []any
+	for {
+		var ind, text, ok = p.peek()
+		if !ok || ind != indent || !isSeqItem(text) {
+			return seq
+		}
+
+		var item = strings.TrimLeft(text[1:], " ")
+		var itemIndent = indent + len(text) - len(item)
+		if isSeqItem(item) {
+			p.lines[p.pos] = strings.Repeat(" ", itemIndent) + item
+			seq = append(seq, p.parseSeq(itemIndent))
+			continue
+		}
+		if isPlainKey(item) {
+			p.lines[p.pos] = strings.Repeat(" ", itemIndent) + item
+			seq = append(seq, p.parseMap(itemIndent))
+			continue
+		}
+
+		p.pos++
+		seq = append(seq, p.parseValue(indent, item, false))
+	}
+}
+
+func (p *yamlParser) parseValue(indent int, rest string, inMap bool) any {
+
+	if rest == "" {
+		var ind, text, ok = p.peek()
+		if !ok {
+			return ""
+		}
+		if ind > indent {
+			if isSeqItem(text) {
+				return p.parseSeq(ind)
+			}
+			return p.parseMap(ind)
+		}
+		if inMap && ind == indent && isSeqItem(text) {
+			return p.parseSeq(ind)
+		}
+		return ""
+	}
+
+	if rest[0] == '|' || rest[0] == '>' {
+		return p.parseBlockScalar(indent, rest)
+	}
+
+	if rest[0] == '[' && strings.HasSuffix(rest, "]") {
+		var seq = []any{}
+		for _, val := range strings.Split(rest[1:len(rest)-1], ",") {
+			if val = strings.TrimSpace(val); val != "" {
+				seq = append(seq, parseScalar(val))
+			}
+		}
+		return seq
+	}
+
+	return parseScalar(rest)
+}
+
+func (p *yamlParser) parseBlockScalar(indent int, header string) string {
+
+	var lines []string
+	var blockIndent = -1
+
+	for ; p.pos < len(p.lines); p.pos++ {
+
+		var line = strings.TrimRight(p.lines[p.pos], " \t")
+		var text = strings.TrimLeft(line, " ")
+		if text == "" {
+			lines = append(lines, "")
+			continue
+		}
+
+		var ind = len(line) - len(text)
+		if ind <= indent {
+			break
+		}
+		if blockIndent < 0 {
+			blockIndent = ind
+		}
+		if ind < blockIndent {
+			break
+		}
+
+		lines = append(lines, line[blockIndent:])
+	}
+
+	var last = len(lines)
+	for last > 0 && lines[last-1] == "" {
+		last--
+	}
+
+	var value string
+	if header[0] == '|' {
+		value = strings.Join(lines[:last], "\n")
+	} else {
+		value = strings.ReplaceAll(strings.Join(lines[:last], "\n"), "\n", " ")
+	}
+
+	if strings.Contains(header, "-") || value == "" {
+		return value
+	}
+	if strings.Contains(header, "+") {
+		return value + strings.Repeat("\n", len(lines)-last+1)
+	}
+	return value + "\n"
+}
+
+func parseScalar(text string) string {
+
+	if len(text) >= 2 && text[0] == '"' {
+		if i := strings.LastIndexByte(text, '"'); i > 0 {
+			if value, err := strconv.Unquote(text[:i+1]); err == nil {
+				return value
+			}
+			return text[1:i]
+		}
+	}
+
+	if len(text) >= 2 && text[0] == '\'' {
+		if i := strings.LastIndexByte(text, '\''); i > 0 {
+			return strings.ReplaceAll(text[1:i], "''", "'")
+		}
+	}
+
+	if i := strings.Index(text, " #"); i >= 0 {
+		text = strings.TrimRight(text[:i], " ")
+	}
+
+	return text
+}
+
+func splitKey(text string) (string, string) {
+
+	if text == "" {
+		return "", ""
+	}
+
+	var start = 0
+	if text[0] == '"' || text[0] == '\'' {
+		if i := strings.IndexByte(text[1:], text[0]); i >= 0 {
+			start = i + 2
+		}
+	}
+
+	var i = strings.Index(text[start:], ": ")
+	if i < 0 {
+		if !strings.HasSuffix(text, ":") {
+			return "", text
+		}
+		i = len(text) - 1 - start
+	}
+
+	return parseScalar(text[:start+i]), strings.TrimSpace(text[start+i+1:])
+}
+
+func isPlainKey(text string) bool {
+
+	var i = strings.Index(text, ": ")
+	if i < 0 {
+		if !strings.HasSuffix(text, ":") {
+			return false
+		}
+		i = len(text) - 1
+	}
+
+	return i > 0 && !strings.ContainsAny(text[:i], " \t\"'`$\\|&;<>()[]{}*?!=#")
+}
+
+func isSeqItem(text string) bool {
+	return text == "-" || strings.HasPrefix(text, "- ")
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseYAML(t *testing.T) {
+
+	var tests = []struct {
+		name string
+		data string
+		want map[string]any
+	}{
+		{"empty", "", map[string]any{}},
+		{"comments", "# comment\n---\na: 1 # trailing\n", map[string]any{"a": "1"}},
+		{"nested map", "job:\n  stage: test\n  tags:\n    - linux\n", map[string]any{
+			"job": map[string]any{"stage": "test", "tags": []any{"linux"}},
+		}},
+		{"sequence at key indent", "script:\n- make\n- make test\n", map[string]any{"script": []any{"make", "make test"}}},
+		{"double quotes", `a: "x: \"y\"\n"` + "\n", map[string]any{"a": "x: \"y\"\n"}},
+		{"single quotes", "a: 'it''s # not a comment'\n", map[string]any{"a": "it's # not a comment"}},
+		{"quoted key", "\"a: b\": c\n", map[string]any{"a: b": "c"}},
+		{"flow sequence", "a: [x, 'y', \"z\"]\nb: []\n", map[string]any{"a": []any{"x", "y", "z"}, "b": []any{}}},
+		{"literal block", "a: |\n  one\n  two\n\nb: x\n", map[string]any{"a": "one\ntwo\n", "b": "x"}},
+		{"folded block", "a: >\n  one\n  two\n", map[string]any{"a": "one two\n"}},
+		{"strip block", "a: |-\n  one\n", map[string]any{"a": "one"}},
+		{"keep block", "a: |+\n  one\n\n", map[string]any{"a": "one\n\n"}},
+		{"block scalar item", "a:\n  - |\n    echo one\n    echo two\n  - echo three\n", map[string]any{
+			"a": []any{"echo one\necho two\n", "echo three"},
+		}},
+		{"map items", "hooks:\n  - name: x\n    when: always\n  - name: y\n", map[string]any{
+			"hooks": []any{map[string]any{"name": "x", "when": "always"}, map[string]any{"name": "y"}},
+		}},
+		{"bare item with map", "hooks:\n  -\n    name: x\n", map[string]any{
+			"hooks": []any{map[string]any{"name": "x"}},
+		}},
+		{"bare item with sequence", "a:\n  -\n    - x\n    - y\n", map[string]any{
+			"a": []any{[]any{"x", "y"}},
+		}},
+		{"nested sequence", "a:\n  - - x\n    - y\n  - z\n", map[string]any{
+			"a": []any{[]any{"x", "y"}, "z"},
+		}},
+		{"empty item", "a:\n  -\n  - x\n", map[string]any{"a": []any{"", "x"}}},
+		{"crlf", "a: 1\r\nb: 2\r\n", map[string]any{"a": "1", "b": "2"}},
+		{"colon in script items", "script:\n  - make\n  - echo \"status: ok\"\n  - curl -H 'Authorization: Bearer x' http://example.com\n  - FOO=a: b\n", map[string]any{
+			"script": []any{"make", "echo \"status: ok\"", "curl -H 'Authorization: Bearer x' http://example.com", "FOO=a: b"},
+		}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := parseYAML(test.data); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("parseYAML(%q) = %#v, want %#v", test.data, got, test.want)
+			}
+		})
+	}
+}
+
+func TestYAMLScript(t *testing.T) {
+
+	var ci = parseYAML("job:\n  script:\n    - make\n    - echo \"status: ok\"\n")
+	var script, err = yamlScript(ci["job"].(map[string]any)["script"])
+	if err != nil || !reflect.DeepEqual(script, []string{"make", "echo \"status: ok\""}) {
+		t.Errorf("yamlScript() = %q, %v", script, err)
+	}
+
+	ci = parseYAML("job:\n  script:\n    - make\n    - name: x\n")
+	if _, err = yamlScript(ci["job"].(map[string]any)["script"]); err == nil {
+		t.Error("yamlScript() accepted a map item")
+	}
+
+	if script, err = yamlScript(nil); script != nil || err != nil {
+		t.Errorf("yamlScript(nil) = %q, %v", script, err)
+	}
+}